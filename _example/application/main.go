@@ -3,7 +3,7 @@ package main
 // example of application calling jrpc server (plugin) providing storage functionality with ""store.save" and ""store.load"
 
 import (
-	"encoding/json"
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -25,30 +25,26 @@ func main() {
 		AuthPasswd: "password",
 	}
 
-	// save record to plugin's store
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// save record to plugin's store, the result decoded to string
 	rec := dataRecord{time.Now(), "12345"}
-	resp, err := rpcClient.Call("store.save", rec)
+	recID, err := jrpc.Invoke[string](ctx, &rpcClient, "store.save", rec)
 	if err != nil {
 		panic(err)
 	}
-	var recID string
-	if err = json.Unmarshal(*resp.Result, &recID); err != nil {
-		panic(err)
-	}
 	log.Printf("stored %+v with id=%s", rec, recID)
 
 	// load record from plugin's store by recID
-	if resp, err = rpcClient.Call("store.load", recID); err != nil {
-		panic(err)
-	}
-	if err = json.Unmarshal(*resp.Result, &rec); err != nil {
+	if rec, err = jrpc.Invoke[dataRecord](ctx, &rpcClient, "store.load", recID); err != nil {
 		panic(err)
 	}
 
 	log.Printf("loaded %+v from id=%s", rec, recID)
 
 	// try to load a record with invalid ID
-	if resp, err = rpcClient.Call("store.load", "something"); err != nil {
+	if _, err = jrpc.Invoke[dataRecord](ctx, &rpcClient, "store.load", "something"); err != nil {
 		log.Printf("can't load for id=something, %s", err)
 	}
 }
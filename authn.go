@@ -0,0 +1,156 @@
+package jrpc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnauthenticated returned by authenticators for requests without valid credentials
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator resolves the principal, i.e. the name of the caller, from the http request.
+// Request rejected with 401 if the error returned, see WithAuthenticator.
+type Authenticator interface {
+	Authenticate(r *http.Request) (principal string, err error)
+}
+
+// AuthenticatorFunc is an adapter to use ordinary functions as Authenticator, i.e. for custom auth schemes
+type AuthenticatorFunc func(r *http.Request) (string, error)
+
+// Authenticate calls f(r)
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, error) { return f(r) }
+
+// BasicAuth is Authenticator of basic auth users, with user name as the key and password as the value.
+// Password can be set as bcrypt hash, i.e. "$2a$10$...". The principal is the user name.
+type BasicAuth map[string]string
+
+// Authenticate checks basic auth credentials of the request. Plain passwords compared in constant time, and if any
+// password is bcrypt hash, unknown users and users with plain password checked against dummy bcrypt hash as well,
+// so the time doesn't tell if the user exists.
+func (b BasicAuth) Authenticate(r *http.Request) (string, error) {
+	user, passwd, ok := r.BasicAuth()
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+	expected, known := b[user]
+	if known && isBcrypt(expected) {
+		if bcrypt.CompareHashAndPassword([]byte(expected), []byte(passwd)) != nil {
+			return "", ErrUnauthenticated
+		}
+		return user, nil
+	}
+	if cost := b.bcryptCost(); cost > 0 {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(cost), []byte(passwd))
+	}
+	// compared even for unknown user, so the time doesn't tell if the user exists
+	if !secureEqual(expected, passwd) || !known {
+		return "", ErrUnauthenticated
+	}
+	return user, nil
+}
+
+// bcryptCost returns the max cost of bcrypt hashed passwords, zero if there are none
+func (b BasicAuth) bcryptCost() int {
+	res := 0
+	for _, passwd := range b {
+		if !strings.HasPrefix(passwd, "$2") {
+			continue
+		}
+		if cost, err := bcrypt.Cost([]byte(passwd)); err == nil {
+			res = max(res, cost)
+		}
+	}
+	return res
+}
+
+// dummyHashes keeps bcrypt hash of a random password for each cost, see dummyHash
+var dummyHashes sync.Map
+
+// dummyHash returns bcrypt hash with given cost, made once for each cost. Nothing matches it,
+// compared to spend the same time as checking of the real hash.
+func dummyHash(cost int) []byte {
+	if h, ok := dummyHashes.Load(cost); ok {
+		return h.([]byte)
+	}
+	passwd := make([]byte, 32)
+	_, _ = rand.Read(passwd)
+	h, err := bcrypt.GenerateFromPassword(passwd, cost)
+	if err != nil {
+		h = []byte{} // can't happen for the cost of the valid hash, compare fails anyway
+	}
+	actual, _ := dummyHashes.LoadOrStore(cost, h)
+	return actual.([]byte)
+}
+
+// BearerTokens is Authenticator of "Authorization: Bearer <token>" header, with token as the key
+// and the principal as the value
+type BearerTokens map[string]string
+
+// Authenticate checks bearer token of the request against all the tokens, in constant time
+func (b BearerTokens) Authenticate(r *http.Request) (string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", ErrUnauthenticated
+	}
+	principal, found := "", false
+	for t, p := range b { // no early exit, all the tokens compared
+		if secureEqual(t, token) {
+			principal, found = p, true
+		}
+	}
+	if !found {
+		return "", ErrUnauthenticated
+	}
+	return principal, nil
+}
+
+// AuthChain is Authenticator trying all the authenticators in order, the first succeeded one wins,
+// i.e. to accept both basic auth and bearer tokens
+type AuthChain []Authenticator
+
+// Authenticate returns the principal of the first succeeded authenticator, or the error of the last one
+func (c AuthChain) Authenticate(r *http.Request) (string, error) {
+	err := ErrUnauthenticated
+	for _, a := range c {
+		var principal string
+		if principal, err = a.Authenticate(r); err == nil {
+			return principal, nil
+		}
+	}
+	return "", err
+}
+
+// isBasic checks if the authenticator is BasicAuth or the chain with BasicAuth,
+// to ask the client for basic auth credentials on rejection
+func isBasic(a Authenticator) bool {
+	switch a := a.(type) {
+	case BasicAuth:
+		return true
+	case AuthChain:
+		return slices.ContainsFunc(a, isBasic)
+	}
+	return false
+}
+
+// secureEqual compares strings in constant time, sha256 hashes compared to hide the length as well
+func secureEqual(a, b string) bool {
+	ha, hb := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// isBcrypt checks if the password is bcrypt hash
+func isBcrypt(passwd string) bool {
+	if !strings.HasPrefix(passwd, "$2") {
+		return false
+	}
+	_, err := bcrypt.Cost([]byte(passwd))
+	return err == nil
+}
@@ -0,0 +1,197 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret2"), bcrypt.MinCost)
+	require.NoError(t, err)
+	a := BasicAuth{"user1": "secret1", "user2": string(hash)}
+
+	tbl := []struct {
+		user, passwd string
+		noAuth       bool
+		principal    string
+	}{
+		{user: "user1", passwd: "secret1", principal: "user1"},
+		{user: "user2", passwd: "secret2", principal: "user2"},
+		{user: "user1", passwd: "secret2"},
+		{user: "user2", passwd: string(hash)},
+		{user: "user3", passwd: "secret1"},
+		{user: "user3", passwd: ""},
+		{noAuth: true},
+	}
+	for _, tt := range tbl {
+		req := httptest.NewRequest("POST", "/", http.NoBody)
+		if !tt.noAuth {
+			req.SetBasicAuth(tt.user, tt.passwd)
+		}
+		principal, err := a.Authenticate(req)
+		if tt.principal == "" {
+			assert.ErrorIs(t, err, ErrUnauthenticated, tt.user+":"+tt.passwd)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.principal, principal)
+	}
+}
+
+func TestBasicAuthUnknownUserTime(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), 8)
+	require.NoError(t, err)
+	a := BasicAuth{"user1": string(hash), "user2": "plain"}
+
+	// min of a few attempts, to be robust to scheduling
+	minTime := func(user string) time.Duration {
+		res := time.Duration(math.MaxInt64)
+		for range 5 {
+			req := httptest.NewRequest("POST", "/", http.NoBody)
+			req.SetBasicAuth(user, "bad")
+			st := time.Now()
+			_, err := a.Authenticate(req)
+			require.ErrorIs(t, err, ErrUnauthenticated)
+			res = min(res, time.Since(st))
+		}
+		return res
+	}
+	known := minTime("user1")
+	assert.Greater(t, minTime("unknown"), known/2, "unknown user checked against bcrypt hash too")
+	assert.Greater(t, minTime("user2"), known/2, "plain password user checked against bcrypt hash too")
+	assert.Equal(t, 8, a.bcryptCost())
+	assert.Equal(t, 0, BasicAuth{"user": "plain"}.bcryptCost())
+}
+
+func TestBearerTokens(t *testing.T) {
+	a := BearerTokens{"token1": "svc1", "token2": "svc2"}
+	tbl := []struct {
+		header, principal string
+	}{
+		{"Bearer token1", "svc1"},
+		{"Bearer token2", "svc2"},
+		{"Bearer token3", ""},
+		{"Bearer ", ""},
+		{"token1", ""},
+		{"Basic dXNlcjpwYXNzd2Q=", ""},
+		{"", ""},
+	}
+	for _, tt := range tbl {
+		req := httptest.NewRequest("POST", "/", http.NoBody)
+		req.Header.Set("Authorization", tt.header)
+		principal, err := a.Authenticate(req)
+		if tt.principal == "" {
+			assert.ErrorIs(t, err, ErrUnauthenticated, tt.header)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.principal, principal)
+	}
+}
+
+func TestAuthChain(t *testing.T) {
+	errCustom := errors.New("no api key")
+	apiKey := AuthenticatorFunc(func(r *http.Request) (string, error) {
+		if r.Header.Get("X-Api-Key") == "key" {
+			return "api", nil
+		}
+		return "", errCustom
+	})
+	a := AuthChain{BasicAuth{"user": "passwd"}, BearerTokens{"token": "svc"}, apiKey}
+
+	req := httptest.NewRequest("POST", "/", http.NoBody)
+	req.SetBasicAuth("user", "passwd")
+	principal, err := a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "user", principal)
+
+	req = httptest.NewRequest("POST", "/", http.NoBody)
+	req.Header.Set("Authorization", "Bearer token")
+	principal, err = a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "svc", principal)
+
+	req = httptest.NewRequest("POST", "/", http.NoBody)
+	req.Header.Set("X-Api-Key", "key")
+	principal, err = a.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "api", principal)
+
+	_, err = a.Authenticate(httptest.NewRequest("POST", "/", http.NoBody))
+	assert.ErrorIs(t, err, errCustom)
+
+	_, err = AuthChain{}.Authenticate(req)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestServerAuthenticator(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret2"), bcrypt.MinCost)
+	require.NoError(t, err)
+	s := NewServer("/v1/cmd", Auth("ignored", "ignored"), WithAuthenticator(AuthChain{
+		BasicAuth{"user1": "secret1", "user2": string(hash)},
+		BearerTokens{"token": "svc"},
+	}))
+	s.AddContext("whoami", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, PrincipalFromContext(ctx), nil)
+	})
+	url := startServer(t, s)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		c         *Client
+		principal string
+	}{
+		{&Client{API: url + "/v1/cmd", AuthUser: "user1", AuthPasswd: "secret1"}, "user1"},
+		{&Client{API: url + "/v1/cmd", AuthUser: "user2", AuthPasswd: "secret2"}, "user2"},
+		{&Client{API: url + "/v1/cmd", AuthToken: "token"}, "svc"},
+		{&Client{API: url + "/v1/cmd", AuthUser: "user1", AuthPasswd: "bad", AuthToken: "token"}, "svc"},
+	} {
+		principal, err := Invoke[string](ctx, tt.c, "whoami")
+		require.NoError(t, err)
+		assert.Equal(t, tt.principal, principal)
+	}
+
+	for _, c := range []*Client{
+		{API: url + "/v1/cmd", AuthUser: "user1", AuthPasswd: "secret2"},
+		{API: url + "/v1/cmd", AuthUser: "ignored", AuthPasswd: "ignored"},
+		{API: url + "/v1/cmd", AuthToken: "bad"},
+		{API: url + "/v1/cmd"},
+	} {
+		_, err := c.Call("whoami")
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+	}
+}
+
+func TestServerAuthenticatorChallenge(t *testing.T) {
+	for _, tt := range []struct {
+		opt       Option
+		challenge string
+	}{
+		{Auth("user", "passwd"), `Basic realm="Restricted"`},
+		{WithAuthenticator(BasicAuth{"user": "passwd"}), `Basic realm="Restricted"`},
+		{WithAuthenticator(BearerTokens{"token": "svc"}), ""},
+		{WithAuthenticator(AuthChain{BearerTokens{"token": "svc"}, BasicAuth{"user": "passwd"}}), `Basic realm="Restricted"`},
+	} {
+		s := NewServer("/v1/cmd", tt.opt)
+		s.Add("fn", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "ok", nil) })
+		url := startServer(t, s)
+
+		resp, err := http.Post(url+"/v1/cmd", "application/json", http.NoBody)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, tt.challenge, resp.Header.Get("WWW-Authenticate"))
+	}
+}
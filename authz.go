@@ -0,0 +1,73 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Authorizer decides if the caller allowed to call the method. The caller identified by the principal
+// of the call, see PrincipalFromContext. Returned error denies the call, see WithAuthorizer.
+type Authorizer interface {
+	Authorize(ctx context.Context, method string) error
+}
+
+// AuthorizerFunc is an adapter to use ordinary functions as Authorizer
+type AuthorizerFunc func(ctx context.Context, method string) error
+
+// Authorize calls f(ctx, method)
+func (f AuthorizerFunc) Authorize(ctx context.Context, method string) error { return f(ctx, method) }
+
+// AllowList is Authorizer with the list of allowed methods for each principal. Method is either the exact
+// method name, i.e. "store.load", the prefix of a group with ".*", i.e. "store.*", or "*" for all methods.
+// Principal not in the list is not allowed to call anything. Empty principal stands for unauthenticated callers.
+type AllowList map[string][]string
+
+// Authorize checks if the principal of ctx allowed to call the method
+func (a AllowList) Authorize(ctx context.Context, method string) error {
+	principal := PrincipalFromContext(ctx)
+	for _, pattern := range a[principal] {
+		if pattern == "*" || pattern == method ||
+			(strings.HasSuffix(pattern, ".*") && strings.HasPrefix(method, strings.TrimSuffix(pattern, "*"))) {
+			return nil
+		}
+	}
+	if principal == "" {
+		principal = "anonymous"
+	}
+	return NewError(ErrCodeForbidden, fmt.Sprintf("%s not allowed to call %s", principal, method))
+}
+
+type principalCtxKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx with the principal, i.e. the name of authenticated caller.
+// Set by the server for authenticated calls, can be used by custom auth middlewares as well.
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the call, i.e. basic auth user name.
+// Returns empty string for unauthenticated calls.
+func PrincipalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalCtxKey{}).(string)
+	return principal
+}
+
+// authorize is the interceptor denying calls not allowed by the authorizer. Denial sent as Error with
+// ErrCodeForbidden code, unless the authorizer returned Error with its own code.
+func (s *Server) authorize(next ContextServerFn) ContextServerFn {
+	return func(ctx context.Context, id uint64, params json.RawMessage) Response {
+		err := s.authorizer.Authorize(ctx, MethodFromContext(ctx))
+		if err == nil {
+			return next(ctx, id, params)
+		}
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			err = NewError(ErrCodeForbidden, err.Error())
+		}
+		s.logger.Logf("[WARN] call %s denied: %v", MethodFromContext(ctx), err)
+		return EncodeResponse(id, nil, err)
+	}
+}
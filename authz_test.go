@@ -0,0 +1,148 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowList(t *testing.T) {
+	a := AllowList{
+		"reader": {"store.load", "rpc.methods"},
+		"writer": {"store.*"},
+		"admin":  {"*"},
+		"":       {"ping"},
+	}
+	tbl := []struct {
+		principal, method string
+		err               string
+	}{
+		{"reader", "store.load", ""},
+		{"reader", "rpc.methods", ""},
+		{"reader", "store.save", "reader not allowed to call store.save"},
+		{"writer", "store.save", ""},
+		{"writer", "store.load", ""},
+		{"writer", "storex.load", "writer not allowed to call storex.load"},
+		{"writer", "ping", "writer not allowed to call ping"},
+		{"admin", "anything", ""},
+		{"", "ping", ""},
+		{"", "store.load", "anonymous not allowed to call store.load"},
+		{"unknown", "store.load", "unknown not allowed to call store.load"},
+	}
+	for _, tt := range tbl {
+		err := a.Authorize(ContextWithPrincipal(context.Background(), tt.principal), tt.method)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.principal+" "+tt.method)
+			continue
+		}
+		assert.EqualError(t, err, tt.err)
+		assert.ErrorIs(t, err, ErrForbidden)
+	}
+}
+
+func TestServerAuthorizer(t *testing.T) {
+	// custom auth middleware, principal is the value of X-User header
+	userHeader := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := r.Header.Get("X-User"); user != "" {
+				r = r.WithContext(ContextWithPrincipal(r.Context(), user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	var seen []string
+	logCalls := func(next ContextServerFn) ContextServerFn {
+		return func(ctx context.Context, id uint64, params json.RawMessage) Response {
+			resp := next(ctx, id, params)
+			seen = append(seen, MethodFromContext(ctx)+":"+resp.Error)
+			return resp
+		}
+	}
+
+	s := NewServer("/v1/cmd", WithMiddlewares(userHeader), WithInterceptors(logCalls),
+		WithAuthorizer(AllowList{"reader": {"store.load"}, "writer": {"store.*"}}))
+	s.Group("store", HandlersGroup{
+		"save": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "saved", nil) },
+		"load": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "loaded", nil) },
+	})
+	s.AddContext("whoami", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, PrincipalFromContext(ctx), nil)
+	})
+	url := startServer(t, s)
+
+	client := func(user string) *Client {
+		c := &Client{API: url + "/v1/cmd"}
+		c.Interceptors = []ClientInterceptor{func(next CallFn) CallFn {
+			return func(ctx context.Context, method string, args []any) (*Response, error) {
+				return next(WithRequestHeader(ctx, "X-User", user), method, args)
+			}
+		}}
+		return c
+	}
+	ctx := context.Background()
+
+	v, err := Invoke[string](ctx, client("reader"), "store.load")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+
+	_, err = client("reader").Call("store.save", "v")
+	require.ErrorIs(t, err, ErrForbidden)
+	assert.EqualError(t, err, "reader not allowed to call store.save")
+
+	v, err = Invoke[string](ctx, client("writer"), "store.save", "v")
+	require.NoError(t, err)
+	assert.Equal(t, "saved", v)
+
+	_, err = client("writer").Call("whoami")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = (&Client{API: url + "/v1/cmd"}).Call("store.load")
+	assert.EqualError(t, err, "anonymous not allowed to call store.load")
+
+	// denied in batch the same way, batches are not intercepted by the client, header set directly
+	resps, err := client("reader").CallBatch(WithRequestHeader(ctx, "X-User", "reader"), BatchCall{Method: "store.load"}, BatchCall{Method: "store.save"})
+	require.NoError(t, err)
+	require.NoError(t, resps[0].Err())
+	assert.ErrorIs(t, resps[1].Err(), ErrForbidden)
+
+	assert.Equal(t, []string{"store.load:", "store.save:reader not allowed to call store.save", "store.save:",
+		"whoami:writer not allowed to call whoami", "store.load:anonymous not allowed to call store.load",
+		"store.load:", "store.save:reader not allowed to call store.save"}, seen, "global interceptors see denied calls")
+}
+
+func TestServerAuthorizerCustomError(t *testing.T) {
+	authz := AuthorizerFunc(func(ctx context.Context, method string) error {
+		switch PrincipalFromContext(ctx) {
+		case "user":
+			return nil
+		case "blocked":
+			return NewError(451, "blocked")
+		default:
+			return errors.New("go away")
+		}
+	})
+	s := NewServer("/v1/cmd", Auth("user", "passwd"), WithAuthorizer(authz))
+	s.AddContext("whoami", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, PrincipalFromContext(ctx), nil)
+	})
+	url := startServer(t, s)
+
+	// principal of basic auth is the user name
+	v, err := Invoke[string](context.Background(), &Client{API: url + "/v1/cmd", AuthUser: "user", AuthPasswd: "passwd"}, "whoami")
+	require.NoError(t, err)
+	assert.Equal(t, "user", v)
+
+	// authorizer errors checked directly, basic auth allows the single user only
+	assert.Equal(t, &Error{Code: 451, Message: "blocked"},
+		authz.Authorize(ContextWithPrincipal(context.Background(), "blocked"), "whoami"))
+
+	resp := s.call(ContextWithPrincipal(context.Background(), "other"), "whoami", 1, nil)
+	assert.Equal(t, &Error{Code: ErrCodeForbidden, Message: "go away"}, resp.Err())
+	resp = s.call(ContextWithPrincipal(context.Background(), "blocked"), "whoami", 1, nil)
+	assert.Equal(t, &Error{Code: 451, Message: "blocked"}, resp.Err())
+}
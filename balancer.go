@@ -0,0 +1,107 @@
+package jrpc
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Balance is a strategy of picking the endpoint for each call, see Client.Endpoints
+type Balance int
+
+// balancing strategies
+const (
+	RoundRobin    Balance = iota // endpoints picked one after another
+	LeastInFlight                // endpoint with the least number of calls in flight picked
+	Random                       // endpoint picked randomly
+)
+
+// balancer keeps the state of load balancing across client endpoints
+type balancer struct {
+	mu       sync.Mutex
+	next     int                  // next endpoint for round-robin
+	inFlight map[string]int       // calls in flight by endpoint
+	ejected  map[string]time.Time // ejected endpoints with ejection end time
+}
+
+func newBalancer() *balancer {
+	return &balancer{inFlight: map[string]int{}, ejected: map[string]time.Time{}}
+}
+
+// pick returns the endpoint for the next attempt of the call. Ejected endpoints, endpoints with open circuit
+// and endpoints already tried by the call skipped, unless there is nothing else left.
+func (b *balancer) pick(endpoints []string, strategy Balance, tried []string, breaker *CircuitBreaker) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	healthy := func(ep string) bool {
+		if until, ok := b.ejected[ep]; ok && now.Before(until) {
+			return false
+		}
+		return breaker == nil || breaker.State(ep) != BreakerOpen
+	}
+	notTried := func(ep string) bool { return !slices.Contains(tried, ep) }
+
+	candidates := filter(endpoints, func(ep string) bool { return healthy(ep) && notTried(ep) })
+	if len(candidates) == 0 {
+		candidates = filter(endpoints, notTried)
+	}
+	if len(candidates) == 0 {
+		candidates = endpoints
+	}
+
+	switch strategy {
+	case LeastInFlight:
+		res := candidates[0]
+		for _, ep := range candidates[1:] {
+			if b.inFlight[ep] < b.inFlight[res] {
+				res = ep
+			}
+		}
+		return res
+	case Random:
+		return candidates[rand.IntN(len(candidates))] //nolint:gosec // no need for crypto rand to pick the endpoint
+	default:
+		// round-robin over all endpoints, so skipped ones don't shift the order of the rest
+		for range endpoints {
+			ep := endpoints[b.next%len(endpoints)]
+			b.next++
+			if slices.Contains(candidates, ep) {
+				return ep
+			}
+		}
+		return candidates[0]
+	}
+}
+
+// acquire marks the call to the endpoint as in flight, returns function to be called once the call finished
+func (b *balancer) acquire(endpoint string) (release func()) {
+	b.mu.Lock()
+	b.inFlight[endpoint]++
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		b.inFlight[endpoint]--
+		b.mu.Unlock()
+	}
+}
+
+// eject excludes the endpoint from picking for the given duration
+func (b *balancer) eject(endpoint string, d time.Duration) {
+	b.mu.Lock()
+	b.ejected[endpoint] = time.Now().Add(d)
+	b.mu.Unlock()
+}
+
+// filter returns elements of the list matching fn
+func filter(list []string, fn func(string) bool) []string {
+	res := make([]string, 0, len(list))
+	for _, v := range list {
+		if fn(v) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
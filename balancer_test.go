@@ -0,0 +1,156 @@
+package jrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Endpoints(t *testing.T) {
+	var down atomic.Bool
+	servers := make([]*httptest.Server, 3)
+	endpoints := make([]string, 3)
+	for i := range servers {
+		servers[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if i == 1 && down.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"result":%d,"id":1}`, i)
+		}))
+		defer servers[i].Close()
+		endpoints[i] = servers[i].URL
+	}
+
+	call := func(t *testing.T, c *Client) int {
+		r, err := c.Call("test")
+		require.NoError(t, err)
+		var res int
+		require.NoError(t, json.Unmarshal(*r.Result, &res))
+		return res
+	}
+
+	t.Run("round-robin", func(t *testing.T) {
+		c := &Client{API: "http://ignored", Endpoints: endpoints}
+		var got []int
+		for range 6 {
+			got = append(got, call(t, c))
+		}
+		assert.Equal(t, []int{0, 1, 2, 0, 1, 2}, got)
+	})
+
+	t.Run("random", func(t *testing.T) {
+		c := &Client{Endpoints: endpoints, Balance: Random}
+		seen := map[int]bool{}
+		for range 100 {
+			seen[call(t, c)] = true
+		}
+		assert.Len(t, seen, 3)
+	})
+
+	t.Run("failed endpoint ejected and retried on another", func(t *testing.T) {
+		down.Store(true)
+		defer down.Store(false)
+
+		c := &Client{Endpoints: endpoints, EjectFor: 100 * time.Millisecond,
+			Retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, Idempotent: []string{"test"}}}
+		var got []int
+		for range 6 {
+			got = append(got, call(t, c))
+		}
+		assert.Equal(t, 0, got[0])
+		assert.Equal(t, 2, got[1], "the second call fails on endpoint 1 and retried on 2")
+		assert.NotContains(t, got, 1)
+		assert.Equal(t, []int{0, 2, 0, 2}, got[2:], "endpoint 1 ejected")
+
+		// not idempotent method not retried, but the endpoint ejected anyway
+		c = &Client{Endpoints: endpoints, EjectFor: 50 * time.Millisecond}
+		assert.Equal(t, 0, call(t, c))
+		_, err := c.Call("test")
+		assert.EqualError(t, err, "bad status 503 Service Unavailable for test")
+		assert.Equal(t, 2, call(t, c))
+		assert.Equal(t, 0, call(t, c))
+		assert.Equal(t, 2, call(t, c))
+
+		// back after ejection time
+		time.Sleep(60 * time.Millisecond)
+		down.Store(false)
+		assert.ElementsMatch(t, []int{0, 1, 2}, []int{call(t, c), call(t, c), call(t, c)})
+	})
+
+	t.Run("breaker open endpoint skipped", func(t *testing.T) {
+		breaker := &CircuitBreaker{Failures: 1, Cooldown: time.Minute}
+		done, err := breaker.allow(endpoints[0])
+		require.NoError(t, err)
+		done(fmt.Errorf("failed"))
+
+		c := &Client{Endpoints: endpoints, Breaker: breaker}
+		var got []int
+		for range 4 {
+			got = append(got, call(t, c))
+		}
+		assert.Equal(t, []int{1, 2, 1, 2}, got)
+	})
+}
+
+func TestBalancer_pick(t *testing.T) {
+	endpoints := []string{"a", "b", "c"}
+
+	t.Run("least in flight", func(t *testing.T) {
+		b := newBalancer()
+		releaseA := b.acquire("a")
+		b.acquire("a")
+		releaseB := b.acquire("b")
+		assert.Equal(t, "c", b.pick(endpoints, LeastInFlight, nil, nil))
+		b.acquire("c")
+		b.acquire("c")
+		assert.Equal(t, "b", b.pick(endpoints, LeastInFlight, nil, nil))
+		releaseB()
+		releaseA()
+		assert.Equal(t, "b", b.pick(endpoints, LeastInFlight, nil, nil))
+		assert.Equal(t, "a", b.pick(endpoints, LeastInFlight, []string{"b"}, nil))
+	})
+
+	t.Run("tried skipped", func(t *testing.T) {
+		b := newBalancer()
+		assert.Equal(t, "b", b.pick(endpoints, RoundRobin, []string{"a"}, nil))
+		assert.Equal(t, "c", b.pick(endpoints, Random, []string{"a", "b"}, nil))
+		assert.Contains(t, endpoints, b.pick(endpoints, RoundRobin, endpoints, nil), "all tried, any can be picked")
+	})
+
+	t.Run("all ejected", func(t *testing.T) {
+		b := newBalancer()
+		for _, ep := range endpoints {
+			b.eject(ep, time.Minute)
+		}
+		assert.Equal(t, "a", b.pick(endpoints, RoundRobin, nil, nil))
+		assert.Equal(t, "c", b.pick(endpoints, RoundRobin, []string{"b"}, nil))
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		b := newBalancer()
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 100 {
+					release := b.acquire(b.pick(endpoints, LeastInFlight, nil, nil))
+					release()
+				}
+			}()
+		}
+		wg.Wait()
+		for _, ep := range endpoints {
+			assert.Equal(t, 0, b.inFlight[ep])
+		}
+	})
+}
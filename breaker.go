@@ -0,0 +1,212 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen returned by the client without calling the server if the circuit breaker of the endpoint is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is a state of the circuit breaker of a single endpoint
+type BreakerState int
+
+// circuit breaker states
+const (
+	BreakerClosed   BreakerState = iota // calls pass through, failures counted
+	BreakerOpen                         // calls rejected with ErrCircuitOpen until Cooldown passed
+	BreakerHalfOpen                     // limited number of probe calls pass through to check if the endpoint recovered
+)
+
+// String returns state name
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops calling the endpoint failing too often, see Client.Breaker. The circuit opens after
+// Failures consecutive failures, or if the ratio of failed calls reaches FailureRatio, and rejects all the calls
+// with ErrCircuitOpen for Cooldown. After that up to Probes calls let through, a successful probe closes the circuit,
+// a failed one opens it again. Failures are transport errors, 5xx and 429 statuses, and calls not finished
+// before the deadline of their context. Errors returned by the remote handler are not failures.
+// The state is kept for each endpoint separately, so a single breaker can be shared by many clients.
+type CircuitBreaker struct {
+	Failures      int                                          // consecutive failures to open the circuit, optional
+	FailureRatio  float64                                      // ratio of failed calls to open the circuit, optional
+	MinCalls      int                                          // min number of calls before FailureRatio checked
+	Interval      time.Duration                                // period of counting calls for FailureRatio, never reset if not set
+	Cooldown      time.Duration                                // time in open state before probe calls, 5s if not set
+	Probes        int                                          // max number of concurrent probe calls, 1 if not set
+	OnStateChange func(endpoint string, from, to BreakerState) // called on each state change, optional
+
+	mu        sync.Mutex
+	endpoints map[string]*breakerEndpoint
+}
+
+// breakerEndpoint is a state of the circuit breaker for a single endpoint
+type breakerEndpoint struct {
+	state       BreakerState
+	changed     time.Time // time of the last state change, used for cooldown
+	windowStart time.Time // start of the current Interval
+	calls       int       // calls in the current Interval
+	failed      int       // failed calls in the current Interval
+	consecutive int       // consecutive failures
+	probes      int       // probes in flight in half-open state
+}
+
+// State returns the current state of the circuit for the endpoint
+func (b *CircuitBreaker) State(endpoint string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := b.endpoint(endpoint)
+	if e.state == BreakerOpen && time.Since(e.changed) >= b.cooldown() {
+		return BreakerHalfOpen
+	}
+	return e.state
+}
+
+// allow checks if the call to the endpoint can be made. Returns ErrCircuitOpen if not,
+// and a function to report the result of the call otherwise.
+func (b *CircuitBreaker) allow(endpoint string) (done func(err error), err error) {
+	b.mu.Lock()
+	e := b.endpoint(endpoint)
+	var from BreakerState
+	changed := false
+
+	if e.state == BreakerOpen {
+		if time.Since(e.changed) < b.cooldown() {
+			b.mu.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		from, changed = e.state, true
+		e.setState(BreakerHalfOpen)
+	}
+
+	probe := e.state == BreakerHalfOpen
+	if probe {
+		if e.probes >= max(b.Probes, 1) { // can't be just changed to half-open, it has no probes yet
+			b.mu.Unlock()
+			return nil, ErrCircuitOpen
+		}
+		e.probes++
+	}
+	b.mu.Unlock()
+	b.notify(endpoint, from, BreakerHalfOpen, changed)
+
+	return func(err error) { b.record(endpoint, probe, err) }, nil
+}
+
+// record counts the result of the call and changes the state if needed
+func (b *CircuitBreaker) record(endpoint string, probe bool, err error) {
+	if errors.Is(err, context.Canceled) {
+		if probe { // canceled probe says nothing about the endpoint, let another one try
+			b.mu.Lock()
+			b.endpoint(endpoint).probes--
+			b.mu.Unlock()
+		}
+		return
+	}
+
+	failed := isBreakerFailure(err)
+	b.mu.Lock()
+	e := b.endpoint(endpoint)
+	from := e.state
+
+	if probe {
+		e.probes--
+		if e.state == BreakerHalfOpen {
+			if failed {
+				e.setState(BreakerOpen)
+			} else {
+				e.setState(BreakerClosed)
+			}
+		}
+		to := e.state
+		b.mu.Unlock()
+		b.notify(endpoint, from, to, from != to)
+		return
+	}
+
+	if e.state != BreakerClosed { // late result of the call made before the circuit opened
+		b.mu.Unlock()
+		return
+	}
+
+	if b.Interval > 0 && time.Since(e.windowStart) >= b.Interval {
+		e.windowStart, e.calls, e.failed = time.Now(), 0, 0
+	}
+	e.calls++
+	if failed {
+		e.failed++
+		e.consecutive++
+	} else {
+		e.consecutive = 0
+	}
+
+	tooManyFailures := b.Failures > 0 && e.consecutive >= b.Failures
+	tooHighRatio := b.FailureRatio > 0 && e.calls >= max(b.MinCalls, 1) && float64(e.failed)/float64(e.calls) >= b.FailureRatio
+	if failed && (tooManyFailures || tooHighRatio) {
+		e.setState(BreakerOpen)
+	}
+	to := e.state
+	b.mu.Unlock()
+	b.notify(endpoint, from, to, from != to)
+}
+
+// endpoint returns state of the endpoint, makes it if not exists. Has to be called under lock.
+func (b *CircuitBreaker) endpoint(endpoint string) *breakerEndpoint {
+	if b.endpoints == nil {
+		b.endpoints = map[string]*breakerEndpoint{}
+	}
+	e, ok := b.endpoints[endpoint]
+	if !ok {
+		now := time.Now()
+		e = &breakerEndpoint{state: BreakerClosed, changed: now, windowStart: now}
+		b.endpoints[endpoint] = e
+	}
+	return e
+}
+
+// notify calls OnStateChange if state changed, has to be called without lock
+func (b *CircuitBreaker) notify(endpoint string, from, to BreakerState, changed bool) {
+	if changed && b.OnStateChange != nil {
+		b.OnStateChange(endpoint, from, to)
+	}
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return 5 * time.Second
+	}
+	return b.Cooldown
+}
+
+// setState changes the state and resets all the counters
+func (e *breakerEndpoint) setState(state BreakerState) {
+	now := time.Now()
+	e.state, e.changed = state, now
+	e.windowStart, e.calls, e.failed, e.consecutive = now, 0, 0, 0
+}
+
+// isBreakerFailure checks if error of the call means the endpoint is not healthy
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Breaker(t *testing.T) {
+	var calls atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if st := int(status.Load()); st != http.StatusOK {
+			w.WriteHeader(st)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"result":"ok","id":1}`)
+	}))
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	breaker := &CircuitBreaker{Failures: 2, Cooldown: 50 * time.Millisecond,
+		OnStateChange: func(endpoint string, from, to BreakerState) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, ts.URL, endpoint)
+			changes = append(changes, from.String()+"->"+to.String())
+		}}
+	c := Client{API: ts.URL, Breaker: breaker, Client: http.Client{Transport: &http.Transport{}}}
+
+	_, err := c.Call("test")
+	require.NoError(t, err)
+
+	// two consecutive failures open the circuit
+	status.Store(http.StatusServiceUnavailable)
+	for range 2 {
+		_, err = c.Call("test")
+		assert.EqualError(t, err, "bad status 503 Service Unavailable for test")
+	}
+	assert.Equal(t, BreakerOpen, breaker.State(ts.URL))
+
+	// open circuit fails fast, without calling the server
+	calls.Store(0)
+	_, err = c.Call("test")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	assert.EqualError(t, err, "circuit breaker is open for test")
+	assert.Equal(t, int32(0), calls.Load())
+
+	// failed probe after cooldown opens it again
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, BreakerHalfOpen, breaker.State(ts.URL))
+	_, err = c.Call("test")
+	assert.EqualError(t, err, "bad status 503 Service Unavailable for test")
+	assert.Equal(t, int32(1), calls.Load())
+	_, err = c.Call("test")
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	// successful probe closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	status.Store(http.StatusOK)
+	_, err = c.Call("test")
+	require.NoError(t, err)
+	assert.Equal(t, BreakerClosed, breaker.State(ts.URL))
+
+	// remote errors and 4xx statuses are not failures
+	status.Store(http.StatusUnauthorized)
+	for range 3 {
+		_, err = c.Call("test")
+		require.Error(t, err)
+	}
+	assert.Equal(t, BreakerClosed, breaker.State(ts.URL))
+
+	mu.Lock()
+	assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}, changes)
+	mu.Unlock()
+}
+
+func TestCircuitBreaker_FailureRatio(t *testing.T) {
+	b := &CircuitBreaker{FailureRatio: 0.5, MinCalls: 4, Interval: time.Hour}
+	failure := errors.New("connection failed")
+
+	call := func(err error) {
+		done, e := b.allow("ep")
+		require.NoError(t, e)
+		done(err)
+	}
+
+	call(nil)
+	call(failure)
+	call(nil)
+	assert.Equal(t, BreakerClosed, b.State("ep"), "not enough calls yet")
+	call(failure)
+	assert.Equal(t, BreakerOpen, b.State("ep"), "2 of 4 failed")
+	assert.Equal(t, BreakerClosed, b.State("other"), "endpoints tracked separately")
+
+	// counts reset after interval
+	b = &CircuitBreaker{FailureRatio: 0.5, MinCalls: 2, Interval: 50 * time.Millisecond}
+	call(failure)
+	time.Sleep(60 * time.Millisecond)
+	call(nil)
+	call(nil)
+	call(failure)
+	assert.Equal(t, BreakerClosed, b.State("ep"), "1 of 3 failed in the current interval")
+}
+
+func TestCircuitBreaker_Probes(t *testing.T) {
+	b := &CircuitBreaker{Failures: 1, Cooldown: time.Millisecond, Probes: 2}
+	done, err := b.allow("ep")
+	require.NoError(t, err)
+	done(errors.New("failed"))
+	require.Equal(t, BreakerOpen, b.State("ep"))
+	time.Sleep(5 * time.Millisecond)
+
+	probe1, err := b.allow("ep")
+	require.NoError(t, err)
+	probe2, err := b.allow("ep")
+	require.NoError(t, err)
+	_, err = b.allow("ep")
+	require.ErrorIs(t, err, ErrCircuitOpen, "only two probes allowed")
+
+	probe1(context.Canceled) // canceled probe releases the slot and doesn't change the state
+	assert.Equal(t, BreakerHalfOpen, b.State("ep"))
+	probe3, err := b.allow("ep")
+	require.NoError(t, err)
+
+	probe2(nil)
+	assert.Equal(t, BreakerClosed, b.State("ep"))
+	probe3(errors.New("late failure"))
+	assert.Equal(t, BreakerClosed, b.State("ep"), "late probe result ignored once closed")
+}
+
+func TestCircuitBreaker_DeadlineIsFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Breaker: &CircuitBreaker{Failures: 1}, Client: http.Client{Transport: &http.Transport{}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.CallContext(ctx, "test")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, BreakerOpen, c.Breaker.State(ts.URL))
+}
@@ -2,75 +2,357 @@ package jrpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Client implements remote engine and delegates all calls to remote http server
-// if AuthUser and AuthPasswd defined will be used for basic auth in each call to server
+// if AuthUser and AuthPasswd defined will be used for basic auth in each call to server.
+// With Endpoints set, calls spread across all of them with Balance strategy, and API ignored.
+// Server listening on unix domain socket, see Server.RunUnix, reached with unix:///path/to/socket:/api url.
 type Client struct {
-	API        string      // URL to jrpc server with entrypoint, i.e. http://127.0.0.1:8080/command
-	Client     http.Client // http client injected by user
-	AuthUser   string      // basic auth user name, should match Server.AuthUser, optional
-	AuthPasswd string      // basic auth password, should match Server.AuthPasswd, optional
+	API        string          // URL to jrpc server with entrypoint, i.e. http://127.0.0.1:8080/command
+	Client     http.Client     // http client injected by user
+	AuthUser   string          // basic auth user name, should match Server.AuthUser, optional
+	AuthPasswd string          // basic auth password, should match Server.AuthPasswd, optional
+	AuthToken  string          // bearer token, sent in Authorization header instead of basic auth if set, optional
+	Sign       *HMACKey        // key signing each request with HMAC, has to match the key of Server's WithHMAC, optional
+	JSONRPC2   bool            // speak json-rpc 2.0, has to be set for servers with WithJSONRPC2 and other 2.0 peers
+	Retry      *RetryPolicy    // retries of failed calls of idempotent methods, optional, no retries if nil
+	Breaker    *CircuitBreaker // circuit breaker failing fast while the server is failing, optional
+	Endpoints  []string        // URLs of server replicas, used instead of API if set, optional
+	Balance    Balance         // strategy of picking the endpoint, RoundRobin by default
+	EjectFor   time.Duration   // time failed endpoint skipped by balancing, 5s if not set
+
+	Interceptors []ClientInterceptor // wrap each call and notification, the first one is the outermost, optional
 
 	id uint64 // used with atomic to populate unique id to Request.ID
+
+	lb struct {
+		once sync.Once
+		*balancer
+	}
+
+	unix unixClients
+}
+
+// notification is Request without id, sent by Notify
+type notification struct {
+	Version string `json:"jsonrpc,omitempty"` // set in json-rpc 2.0 mode only
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// BatchCall defines a single call of the batch, see Client.CallBatch.
+// Args follow the same rules as args of Call.
+type BatchCall struct {
+	Method string
+	Args   []any
 }
 
 // Call remote server with given method and arguments.
 // Empty args will be ignored, single arg will be marshaled as-us and multiple args marshaled as []interface{}.
 // Returns Response and error. Note: Response has it's own Error field, but that onw controlled by server.
-// Returned error represent client-level errors, like failed http call, failed marshaling and so on.
+// Returned error represent client-level errors, like failed http call, failed marshaling and so on,
+// or the remote error as *Error, with code and data if set by the server.
 func (r *Client) Call(method string, args ...any) (*Response, error) {
+	return r.CallContext(context.Background(), method, args...)
+}
 
-	var b []byte
-	var err error
+// CallContext is like Call but carries ctx into the http request. The call is aborted as soon as ctx
+// is canceled or its deadline exceeded, in addition to the limits set by Client.Client itself.
+func (r *Client) CallContext(ctx context.Context, method string, args ...any) (*Response, error) {
+	return r.intercepted(r.call)(ctx, method, args)
+}
 
-	switch {
-	case len(args) == 0:
-		b, err = json.Marshal(Request{Method: method, ID: atomic.AddUint64(&r.id, 1)})
-		if err != nil {
-			return nil, fmt.Errorf("marshaling failed for %s: %w", method, err)
+// call is CallContext without interceptors
+func (r *Client) call(ctx context.Context, method string, args []any) (*Response, error) {
+	b, err := json.Marshal(r.envelope(r.request(method, args)))
+	if err != nil {
+		return nil, fmt.Errorf("marshaling failed for %s: %w", method, err)
+	}
+
+	resp, err := r.post(ctx, method, b, r.isIdempotent(method))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	cr := Response{}
+	if r.JSONRPC2 {
+		cr, err = decodeResponse2(resp.Body)
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(&cr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response for %s: %w", method, err)
+	}
+
+	if err = cr.Err(); err != nil {
+		return nil, err
+	}
+	return &cr, nil
+}
+
+// Notify sends notification, i.e. the call without id, to the remote server. Server runs the handler
+// and doesn't send any response back, so the result and the remote error of the handler are not available.
+// Returned error represents client-level errors, like failed http call or the method unknown to the server.
+func (r *Client) Notify(method string, args ...any) error {
+	return r.NotifyContext(context.Background(), method, args...)
+}
+
+// NotifyContext is like Notify but carries ctx into the http request
+func (r *Client) NotifyContext(ctx context.Context, method string, args ...any) error {
+	_, err := r.intercepted(r.notify)(ctx, method, args)
+	return err
+}
+
+// notify is NotifyContext without interceptors, the response is always nil
+func (r *Client) notify(ctx context.Context, method string, args []any) (*Response, error) {
+	body := notification{Method: method, Params: r.params(args)}
+	if r.JSONRPC2 {
+		body.Version = jsonrpcVersion
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling failed for %s: %w", method, err)
+	}
+
+	resp, err := r.post(ctx, method, b, r.isIdempotent(method))
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body) // some servers respond to notifications with a body, drain it to reuse connection
+	return nil, resp.Body.Close()
+}
+
+// CallBatch sends all the calls in a single http request and returns responses in the order of calls.
+// Returned error represents failure of the whole batch, like failed http call. Remote errors of
+// individual calls don't fail the batch and kept in the corresponding Response, see Response.Err.
+func (r *Client) CallBatch(ctx context.Context, calls ...BatchCall) ([]Response, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	reqs := make([]any, len(calls))
+	ids := make([]uint64, len(calls))
+	methods := make([]string, len(calls))
+	for i, c := range calls {
+		req := r.request(c.Method, c.Args)
+		ids[i] = req.ID
+		reqs[i] = r.envelope(req)
+		methods[i] = c.Method
+	}
+
+	b, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling failed for batch: %w", err)
+	}
+
+	resp, err := r.post(ctx, "batch", b, r.isIdempotent(methods...))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var raws []json.RawMessage
+	if err = json.NewDecoder(resp.Body).Decode(&raws); err != nil {
+		return nil, fmt.Errorf("failed to decode response for batch: %w", err)
+	}
+
+	byID := make(map[uint64]Response, len(raws))
+	for _, raw := range raws {
+		cr := Response{}
+		if r.JSONRPC2 {
+			cr, err = decodeResponse2(bytes.NewReader(raw))
+		} else {
+			err = json.Unmarshal(raw, &cr)
 		}
-	case len(args) == 1:
-		b, err = json.Marshal(Request{Method: method, Params: args[0], ID: atomic.AddUint64(&r.id, 1)})
 		if err != nil {
-			return nil, fmt.Errorf("marshaling failed for %s: %w", method, err)
+			return nil, fmt.Errorf("failed to decode response for batch: %w", err)
 		}
-	default:
-		b, err = json.Marshal(Request{Method: method, Params: args, ID: atomic.AddUint64(&r.id, 1)})
-		if err != nil {
-			return nil, fmt.Errorf("marshaling failed for %s: %w", method, err)
+		byID[cr.ID] = cr
+	}
+
+	res := make([]Response, len(calls))
+	for i, id := range ids {
+		cr, ok := byID[id]
+		if !ok {
+			cr = Response{ID: id, Error: "no response for " + calls[i].Method, ErrorCode: ErrCodeInternal}
+		}
+		res[i] = cr
+	}
+	return res, nil
+}
+
+// request makes Request with the next id for given method and args
+func (r *Client) request(method string, args []any) Request {
+	return Request{Method: method, Params: r.params(args), ID: atomic.AddUint64(&r.id, 1)}
+}
+
+// envelope wraps Request in json-rpc 2.0 envelope if enabled, returns it as-is otherwise
+func (r *Client) envelope(req Request) any {
+	if r.JSONRPC2 {
+		return clientRequest2{Version: jsonrpcVersion, Request: req}
+	}
+	return req
+}
+
+// post sends body to the server and checks response status, any 2xx is fine. With retry set the failed
+// attempts repeated according to the retry policy, each on another endpoint if there are many.
+// Caller has to close response body.
+func (r *Client) post(ctx context.Context, method string, body []byte, retry bool) (*http.Response, error) {
+	var tried []string
+	for attempt := 1; ; attempt++ {
+		endpoint := r.pick(tried)
+		tried = append(tried, endpoint)
+		resp, err := r.send(ctx, endpoint, method, body)
+		if err == nil || !retry || r.Retry == nil || attempt >= r.Retry.MaxAttempts || !r.Retry.isRetryable(err) {
+			return resp, err
+		}
+		if !r.Retry.wait(ctx, attempt) {
+			return nil, err
 		}
 	}
+}
 
-	req, err := http.NewRequest("POST", r.API, bytes.NewBuffer(b))
+// send makes a single http request with body to the endpoint and checks response status.
+// With circuit breaker set the request is not made if the circuit is open.
+// With many endpoints the failed one ejected from balancing for EjectFor.
+func (r *Client) send(ctx context.Context, endpoint, method string, body []byte) (resp *http.Response, err error) {
+	if r.Breaker != nil {
+		done, openErr := r.Breaker.allow(endpoint)
+		if openErr != nil {
+			return nil, fmt.Errorf("%w for %s", openErr, method)
+		}
+		defer func() { done(err) }()
+	}
+
+	if len(r.Endpoints) > 0 {
+		release := r.balancer().acquire(endpoint)
+		defer func() {
+			release()
+			if isBreakerFailure(err) && !errors.Is(err, context.Canceled) {
+				r.balancer().eject(endpoint, r.ejectFor())
+			}
+		}()
+	}
+
+	hc, url, err := r.httpClient(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request for %s: %w", method, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request for %s: %w", method, err)
 	}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range headersFromContext(ctx) {
+		req.Header[k] = v
+	}
+
+	if r.Sign != nil {
+		if err = r.Sign.sign(req, method, body); err != nil {
+			return nil, fmt.Errorf("failed to sign request for %s: %w", method, err)
+		}
+	}
 
-	if r.AuthUser != "" && r.AuthPasswd != "" {
+	switch {
+	case r.AuthToken != "":
+		req.Header.Set("Authorization", "Bearer "+r.AuthToken)
+	case r.AuthUser != "" && r.AuthPasswd != "":
 		req.SetBasicAuth(r.AuthUser, r.AuthPasswd)
 	}
-	resp, err := r.Client.Do(req)
+	resp, err = hc.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("remote call failed for %s: %w", method, err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("bad status %s for %s", resp.Status, method)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Method: method, Err: statusBodyError(resp.Body)}
 	}
+	return resp, nil
+}
 
-	cr := Response{}
-	if err = json.NewDecoder(resp.Body).Decode(&cr); err != nil {
-		return nil, fmt.Errorf("failed to decode response for %s: %w", method, err)
+// statusBodyError decodes coded error from the body of non-2xx response, i.e. {"error":"...","error_code":-32601},
+// nil if the body is not a json with non-zero error_code
+func statusBodyError(body io.Reader) *Error {
+	var cr Response
+	if err := json.NewDecoder(io.LimitReader(body, 64*1024)).Decode(&cr); err != nil || cr.ErrorCode == 0 {
+		return nil
 	}
+	return &Error{Code: cr.ErrorCode, Message: cr.Error, Data: cr.ErrorData}
+}
 
-	if cr.Error != "" {
-		return nil, fmt.Errorf("%s", cr.Error)
+// intercepted wraps fn with all the interceptors, the first one is the outermost
+func (r *Client) intercepted(fn CallFn) CallFn {
+	for i := len(r.Interceptors) - 1; i >= 0; i-- {
+		fn = r.Interceptors[i](fn)
+	}
+	return fn
+}
+
+// pick returns the endpoint for the next attempt, API if there are no Endpoints
+func (r *Client) pick(tried []string) string {
+	if len(r.Endpoints) == 0 {
+		return r.API
+	}
+	return r.balancer().pick(r.Endpoints, r.Balance, tried, r.Breaker)
+}
+
+// balancer returns load balancing state, made on the first use
+func (r *Client) balancer() *balancer {
+	r.lb.once.Do(func() { r.lb.balancer = newBalancer() })
+	return r.lb.balancer
+}
+
+func (r *Client) ejectFor() time.Duration {
+	if r.EjectFor <= 0 {
+		return 5 * time.Second
+	}
+	return r.EjectFor
+}
+
+// isIdempotent checks if all the methods marked as idempotent by the retry policy
+func (r *Client) isIdempotent(methods ...string) bool {
+	if r.Retry == nil {
+		return false
+	}
+	for _, m := range methods {
+		if !r.Retry.isIdempotent(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// params makes request params from args, nil for no args, the arg itself for a single one and the list otherwise.
+// In json-rpc 2.0 mode params have to be an array or an object, so a single arg encoded as anything else,
+// i.e. a string or a number, wrapped in array.
+func (r *Client) params(args []any) any {
+	switch len(args) {
+	case 0:
+		return nil
+	case 1:
+		if !r.JSONRPC2 {
+			return args[0]
+		}
+		b, err := json.Marshal(args[0])
+		if err != nil {
+			return args[0] // fails the same way on marshaling of the request
+		}
+		if b = bytes.TrimSpace(b); len(b) > 0 && (b[0] == '{' || b[0] == '[') {
+			return json.RawMessage(b)
+		}
+		return []json.RawMessage{b}
+	default:
+		return args
 	}
-	return &cr, nil
 }
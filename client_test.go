@@ -1,7 +1,9 @@
 package jrpc
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -83,6 +85,61 @@ func TestClient_CallError(t *testing.T) {
 	assert.EqualError(t, err, "some error")
 }
 
+func TestClient_CallStructuredError(t *testing.T) {
+	ts := testServer(t, `{"method":"test","id":1}`, `{"error":"some error","error_code":-32602,"error_data":[1,2],"id":1}`)
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}}
+	_, err := c.Call("test")
+	assert.EqualError(t, err, "some error")
+	assert.ErrorIs(t, err, ErrInvalidParams)
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, &Error{Code: ErrCodeInvalidParams, Message: "some error", Data: json.RawMessage("[1,2]")}, rpcErr)
+}
+
+func TestClient_CallStatusErrorCodes(t *testing.T) {
+	s := NewServer("/v1/cmd", WithTimeouts(Timeouts{CallTimeout: 50 * time.Millisecond}))
+	s.AddContext("slow", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		<-ctx.Done()
+		return EncodeResponse(id, "too late", nil)
+	})
+	c := Client{API: startServer(t, s) + "/v1/cmd", Client: http.Client{}}
+
+	t.Run("method not found", func(t *testing.T) {
+		_, err := c.Call("blah")
+		assert.EqualError(t, err, "bad status 501 Not Implemented for blah")
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusNotImplemented, statusErr.StatusCode)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, &Error{Code: ErrCodeMethodNotFound, Message: "unsupported method"}, rpcErr)
+	})
+
+	t.Run("call timeout", func(t *testing.T) {
+		_, err := c.Call("slow")
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, &Error{Code: ErrCodeTimeout, Message: "call timeout"}, rpcErr)
+	})
+
+	t.Run("no code in the body", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}))
+		defer plain.Close()
+		_, err := (&Client{API: plain.URL}).Call("test")
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Nil(t, statusErr.Err)
+		var rpcErr *Error
+		assert.False(t, errors.As(err, &rpcErr))
+	})
+}
+
 func TestClient_CallBadResponse(t *testing.T) {
 	ts := testServer(t, `{"method":"test","params":[123,"abc"],"id":1}`, `{"result":"12345 invalid}`)
 	defer ts.Close()
@@ -91,12 +148,84 @@ func TestClient_CallBadResponse(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestClient_CallNullResult(t *testing.T) {
+	tbl := []struct {
+		jsonrpc2   bool
+		resp, want string
+	}{
+		{false, `{"result":null,"id":1}`, "null"},
+		{false, `{"id":1}`, ""},
+		{true, `{"jsonrpc":"2.0","result":null,"id":1}`, "null"},
+		{true, `{"jsonrpc":"2.0","id":1}`, ""},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.resp, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = fmt.Fprint(w, tt.resp)
+			}))
+			defer ts.Close()
+			c := Client{API: ts.URL, JSONRPC2: tt.jsonrpc2}
+			resp, err := c.Call("test")
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Nil(t, resp.Result, "missing result")
+				return
+			}
+			require.NotNil(t, resp.Result, "null result told apart from the missing one")
+			assert.Equal(t, tt.want, string(*resp.Result))
+		})
+	}
+}
+
 func TestClient_CallBadRemote(t *testing.T) {
 	c := Client{API: "http://127.0.0.2", Client: http.Client{Timeout: 10 * time.Millisecond}}
 	_, err := c.Call("test", 123)
 	assert.NotNil(t, err)
 }
 
+func TestClient_CallContext(t *testing.T) {
+	ts := testServer(t, `{"method":"test","params":"abc","id":1}`, `{"result":"12345"}`)
+	defer ts.Close()
+	c := Client{API: ts.URL, Client: http.Client{}}
+	resp, err := c.CallContext(context.Background(), "test", "abc")
+	require.NoError(t, err)
+	res := ""
+	require.NoError(t, json.Unmarshal(*resp.Result, &res))
+	assert.Equal(t, "12345", res)
+}
+
+func TestClient_CallContextCanceled(t *testing.T) {
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(unblock)
+
+	c := Client{API: ts.URL, Client: http.Client{}}
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		st := time.Now()
+		_, err := c.CallContext(ctx, "test", 123)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(st), time.Second)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		_, err := c.CallContext(ctx, "test", 123)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
 func testServer(t *testing.T, req, resp string) *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
@@ -107,3 +236,59 @@ func testServer(t *testing.T, req, resp string) *httptest.Server {
 		require.NoError(t, err)
 	}))
 }
+
+func TestClient_CallBatch(t *testing.T) {
+	ts := testServer(t, `[{"method":"m1","params":1,"id":1},{"method":"m2","params":[1,"a"],"id":2},{"method":"m3","id":3}]`,
+		`[{"result":"r3","id":3},{"error":"some error","error_code":42,"id":2},{"result":"r1","id":1}]`)
+	defer ts.Close()
+
+	c := Client{API: ts.URL}
+	res, err := c.CallBatch(context.Background(),
+		BatchCall{Method: "m1", Args: []any{1}},
+		BatchCall{Method: "m2", Args: []any{1, "a"}},
+		BatchCall{Method: "m3"},
+	)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	assert.NoError(t, res[0].Err())
+	assert.Equal(t, uint64(1), res[0].ID)
+	assert.JSONEq(t, `"r1"`, string(*res[0].Result))
+
+	assert.Equal(t, &Error{Code: 42, Message: "some error"}, res[1].Err())
+	assert.Equal(t, uint64(2), res[1].ID)
+
+	assert.NoError(t, res[2].Err())
+	assert.JSONEq(t, `"r3"`, string(*res[2].Result))
+}
+
+func TestClient_CallBatchJSONRPC2(t *testing.T) {
+	ts := testServer(t, `[{"jsonrpc":"2.0","method":"m1","params":[1],"id":1},{"jsonrpc":"2.0","method":"m2","id":2}]`,
+		`[{"jsonrpc":"2.0","result":"r1","id":1}]`)
+	defer ts.Close()
+
+	c := Client{API: ts.URL, JSONRPC2: true}
+	res, err := c.CallBatch(context.Background(), BatchCall{Method: "m1", Args: []any{1}}, BatchCall{Method: "m2"})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.JSONEq(t, `"r1"`, string(*res[0].Result))
+	assert.Equal(t, &Error{Code: ErrCodeInternal, Message: "no response for m2"}, res[1].Err())
+}
+
+func TestClient_CallBatchFailed(t *testing.T) {
+	c := Client{API: "http://127.0.0.2", Client: http.Client{Timeout: 10 * time.Millisecond}}
+	_, err := c.CallBatch(context.Background(), BatchCall{Method: "m1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remote call failed for batch")
+
+	ts := testServer(t, `[{"method":"m1","id":1}]`, `{"result":"not a batch"}`)
+	defer ts.Close()
+	c = Client{API: ts.URL}
+	_, err = c.CallBatch(context.Background(), BatchCall{Method: "m1"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode response for batch")
+
+	res, err := c.CallBatch(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
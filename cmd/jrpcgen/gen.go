@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/go-pkgz/jrpc"
+)
+
+// options of the generation, empty values replaced with defaults derived from Type
+type options struct {
+	Type     string // name of the interface
+	Prefix   string // prefix (group) of methods
+	Client   string // name of the generated client type
+	Register string // name of the generated register function
+}
+
+// iface is the interface with all the details needed by the template
+type iface struct {
+	options
+	Package string
+	Std     []string // quoted import specs of standard packages, with alias if needed
+	Imports []string // quoted import specs of other packages, with alias if needed
+	Methods []method
+	Fmt     bool // fmt used by the generated code, for results and checking of multiple params
+}
+
+// method is a single method of the interface
+type method struct {
+	Name   string  // go name of the method
+	RPC    string  // remote method name, prefix.method
+	Method string  // method name within the group
+	Ctx    bool    // method takes context.Context as the first param
+	Params []param // params, except context
+	Result string  // type of the returned value, empty if method returns error only
+}
+
+// param is a single param of the method
+type param struct {
+	Name string // name in the client method
+	Type string
+}
+
+// reserved names used by the generated client method, params with these names renamed
+var reserved = map[string]bool{"c": true, "ctx": true, "resp": true, "res": true, "err": true,
+	"context": true, "json": true, "fmt": true, "jrpc": true}
+
+// generate makes formatted source of the client and server adapter for the interface found in dir
+func generate(dir string, opts options) ([]byte, error) {
+	fset := token.NewFileSet()
+	files, pkgName, err := parseDir(fset, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		spec := findInterface(f, opts.Type)
+		if spec == nil {
+			continue
+		}
+		it, err := makeIface(f, spec, withDefaults(opts))
+		if err != nil {
+			return nil, fmt.Errorf("interface %s: %w", opts.Type, err)
+		}
+		it.Package = pkgName
+		return render(it)
+	}
+	return nil, fmt.Errorf("interface %s not found in %s", opts.Type, dir)
+}
+
+// parseDir parses all non-test go files of the package in dir
+func parseDir(fset *token.FileSet, dir string) (files []*ast.File, pkgName string, err error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, "", fmt.Errorf("can't list go files in %s: %w", dir, err)
+	}
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(name) //nolint:gosec // reading package sources is the purpose
+		if err != nil {
+			return nil, "", fmt.Errorf("can't read %s: %w", name, err)
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, "", fmt.Errorf("can't parse %s: %w", name, err)
+		}
+		files = append(files, f)
+		pkgName = f.Name.Name
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("no go files in %s", dir)
+	}
+	return files, pkgName, nil
+}
+
+// findInterface returns the type spec of the interface with given name, nil if not declared in the file
+func findInterface(f *ast.File, name string) *ast.TypeSpec {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gen.Specs {
+			ts := s.(*ast.TypeSpec)
+			if _, isIface := ts.Type.(*ast.InterfaceType); isIface && ts.Name.Name == name {
+				return ts
+			}
+		}
+	}
+	return nil
+}
+
+func withDefaults(opts options) options {
+	if opts.Prefix == "" {
+		opts.Prefix = strings.ToLower(opts.Type)
+	}
+	if opts.Client == "" {
+		opts.Client = opts.Type + "Client"
+	}
+	if opts.Register == "" {
+		opts.Register = "Register" + opts.Type
+	}
+	return opts
+}
+
+// makeIface collects methods of the interface declared in the file f, with imports used by their types
+func makeIface(f *ast.File, spec *ast.TypeSpec, opts options) (iface, error) {
+	if spec.TypeParams != nil {
+		return iface{}, fmt.Errorf("generic interfaces not supported")
+	}
+	res := iface{options: opts}
+	fileImports := imports(f)
+	used := map[string]bool{}
+
+	for _, field := range spec.Type.(*ast.InterfaceType).Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return iface{}, fmt.Errorf("embedded %s not supported", types.ExprString(field.Type))
+		}
+		m, err := makeMethod(field.Names[0].Name, fn, fileImports)
+		if err != nil {
+			return iface{}, fmt.Errorf("method %s: %w", field.Names[0].Name, err)
+		}
+		m.Method = jrpc.MethodName(m.Name)
+		m.RPC = opts.Prefix + "." + m.Method
+		res.Fmt = res.Fmt || m.Result != "" || len(m.Params) > 1
+		res.Methods = append(res.Methods, m)
+
+		for i, p := range fn.Params.List {
+			if i == 0 && m.Ctx {
+				continue // context imported by the template
+			}
+			collectImports(p.Type, fileImports, used)
+		}
+		for _, r := range fn.Results.List {
+			collectImports(r.Type, fileImports, used)
+		}
+	}
+	if len(res.Methods) == 0 {
+		return iface{}, fmt.Errorf("no methods")
+	}
+
+	// packages imported by the template itself
+	if used[strconv.Quote("fmt")] {
+		res.Fmt = true
+	}
+	for _, p := range []string{"context", "encoding/json", "fmt", "github.com/go-pkgz/jrpc"} {
+		delete(used, strconv.Quote(p))
+	}
+	for spec := range used {
+		p := spec[strings.Index(spec, `"`)+1:]
+		if strings.Contains(strings.Split(p, "/")[0], ".") {
+			res.Imports = append(res.Imports, spec)
+			continue
+		}
+		res.Std = append(res.Std, spec)
+	}
+	sort.Strings(res.Std)
+	sort.Strings(res.Imports)
+	return res, nil
+}
+
+// makeMethod checks the signature of the method and converts it to method
+func makeMethod(name string, fn *ast.FuncType, fileImports map[string]string) (method, error) {
+	m := method{Name: name}
+
+	params := fn.Params.List
+	if len(params) > 0 && isContext(params[0].Type, fileImports) {
+		m.Ctx = true
+		if len(params[0].Names) > 1 { // i.e. Save(ctx, other context.Context)
+			return method{}, fmt.Errorf("only the first param can be context.Context")
+		}
+		params = params[1:]
+	}
+
+	for _, p := range params {
+		if _, ok := p.Type.(*ast.Ellipsis); ok {
+			return method{}, fmt.Errorf("variadic params not supported")
+		}
+		if _, ok := p.Type.(*ast.FuncType); ok {
+			return method{}, fmt.Errorf("func params not supported")
+		}
+		typ := types.ExprString(p.Type)
+		if len(p.Names) == 0 {
+			m.Params = append(m.Params, param{Type: typ})
+		}
+		for _, n := range p.Names {
+			m.Params = append(m.Params, param{Name: n.Name, Type: typ})
+		}
+	}
+	for i, p := range m.Params {
+		if p.Name == "" || p.Name == "_" || reserved[p.Name] {
+			m.Params[i].Name = fmt.Sprintf("p%d", i)
+		}
+	}
+
+	var results []ast.Expr
+	if fn.Results != nil {
+		for _, r := range fn.Results.List {
+			for range max(len(r.Names), 1) {
+				results = append(results, r.Type)
+			}
+		}
+	}
+	if len(results) == 0 || len(results) > 2 || types.ExprString(results[len(results)-1]) != "error" {
+		return method{}, fmt.Errorf("has to return error or a value and error")
+	}
+	if len(results) == 2 {
+		m.Result = types.ExprString(results[0])
+	}
+	return m, nil
+}
+
+// imports returns import specs of the file by package name used in the code
+func imports(f *ast.File) map[string]string {
+	res := map[string]string{}
+	for _, imp := range f.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		if imp.Name != nil {
+			res[imp.Name.Name] = imp.Name.Name + " " + imp.Path.Value
+			continue
+		}
+		res[pkgName(p)] = imp.Path.Value
+	}
+	return res
+}
+
+// pkgName guesses package name from import path, the last element without major version suffix
+func pkgName(importPath string) string {
+	name := path.Base(importPath)
+	if strings.HasPrefix(name, "v") && strings.Trim(name[1:], "0123456789") == "" && name != "v" {
+		name = path.Base(path.Dir(importPath)) // i.e. github.com/user/pkg/v2
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 {
+		name = name[:i] // i.e. gopkg.in/yaml.v3
+	}
+	return name
+}
+
+// collectImports adds import specs of all the packages referenced by the type expression to used
+func collectImports(expr ast.Expr, fileImports map[string]string, used map[string]bool) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		if id, ok := sel.X.(*ast.Ident); ok {
+			if spec, ok := fileImports[id.Name]; ok {
+				used[spec] = true
+			}
+		}
+		return false
+	})
+}
+
+// isContext checks if the type expression is context.Context
+func isContext(expr ast.Expr, fileImports map[string]string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Context" {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && strings.HasSuffix(fileImports[id.Name], strconv.Quote("context"))
+}
+
+// render executes the template and formats the result
+func render(it iface) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, it); err != nil {
+		return nil, fmt.Errorf("can't execute template: %w", err)
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("can't format generated code: %w", err)
+	}
+	return src, nil
+}
+
+var tmpl = template.Must(template.New("jrpc").Parse(`// Code generated by jrpcgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+{{- if .Fmt}}
+	"fmt"
+{{- end}}
+{{- range .Std}}
+	{{.}}
+{{- end}}
+
+	"github.com/go-pkgz/jrpc"
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+
+// {{.Client}} implements {{.Type}} by calls to remote jrpc server, methods named "{{.Prefix}}.*"
+type {{.Client}} struct {
+	Client *jrpc.Client
+}
+
+var _ {{.Type}} = (*{{.Client}})(nil)
+{{range $m := .Methods}}
+// {{.Name}} calls remote {{.RPC}}
+func (c *{{$.Client}}) {{.Name}}({{if .Ctx}}ctx context.Context{{if .Params}}, {{end}}{{end}}
+	{{- range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{$p.Type}}{{end}}) ({{if .Result}}{{.Result}}, {{end}}error) {
+{{- if .Result}}
+	var res {{.Result}}
+	resp, err := c.Client.{{if .Ctx}}CallContext(ctx, {{else}}Call({{end}}"{{.RPC}}"{{range .Params}}, {{.Name}}{{end}})
+	if err != nil {
+		return res, err
+	}
+	if resp.Result == nil {
+		return res, fmt.Errorf("%w for {{.RPC}}", jrpc.ErrNoResult)
+	}
+	if err = json.Unmarshal(*resp.Result, &res); err != nil {
+		return res, fmt.Errorf("failed to decode result for {{.RPC}}: %w", err)
+	}
+	return res, nil
+{{- else}}
+	_, err := c.Client.{{if .Ctx}}CallContext(ctx, {{else}}Call({{end}}"{{.RPC}}"{{range .Params}}, {{.Name}}{{end}})
+	return err
+{{- end}}
+}
+{{end}}
+// {{.Register}} registers methods of impl with the server as "{{.Prefix}}" group, i.e. "{{(index .Methods 0).RPC}}".
+// Params which can't be decoded reported to the caller as jrpc.Error with jrpc.ErrCodeInvalidParams code.
+func {{.Register}}(s *jrpc.Server, impl {{.Type}}) {
+	s.GroupContext("{{.Prefix}}", jrpc.ContextHandlersGroup{
+{{- range $m := .Methods}}
+		"{{.Method}}": func({{if .Ctx}}ctx{{else}}_{{end}} context.Context, id uint64, {{if .Params}}params{{else}}_{{end}} json.RawMessage) jrpc.Response {
+{{- if eq (len .Params) 1}}
+			var p0 {{(index .Params 0).Type}}
+			if err := jrpc.DecodeParams(params, &p0); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+{{- else if .Params}}
+			var args []json.RawMessage
+			if err := jrpc.DecodeParams(params, &args); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			if len(args) != {{len .Params}} {
+				return jrpc.EncodeResponse(id, nil, jrpc.NewError(jrpc.ErrCodeInvalidParams,
+					fmt.Sprintf("invalid params: expected {{len .Params}}, got %d", len(args))))
+			}
+{{- range $i, $p := .Params}}
+			var p{{$i}} {{$p.Type}}
+			if err := jrpc.DecodeParams(args[{{$i}}], &p{{$i}}); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+{{- end}}
+{{- end}}
+{{- if .Result}}
+			res, err := impl.{{.Name}}({{template "args" .}})
+			return jrpc.EncodeResponse(id, res, err)
+{{- else}}
+			return jrpc.EncodeResponse(id, nil, impl.{{.Name}}({{template "args" .}}))
+{{- end}}
+		},
+{{- end}}
+	})
+}
+{{define "args"}}{{if .Ctx}}ctx{{if .Params}}, {{end}}{{end}}{{range $i, $p := .Params}}{{if $i}}, {{end}}p{{$i}}{{end}}{{end}}
+`))
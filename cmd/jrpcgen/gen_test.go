@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateStore(t *testing.T) {
+	// generated code of the example committed and tested in internal/store, has to be up to date
+	src, err := generate("internal/store", options{Type: "Store"})
+	require.NoError(t, err)
+	expected, err := os.ReadFile("internal/store/store_jrpc.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(src))
+}
+
+func TestGenerateOptions(t *testing.T) {
+	dir := writePkg(t, `package svc
+
+import (
+	"context"
+	ext "github.com/example/types"
+	"gopkg.in/yaml.v3"
+)
+
+type Service interface {
+	GetByID(ctx context.Context, id string) (*ext.Item, error)
+	Parse(_ yaml.Node, c int, json string) error
+}
+`)
+	src, err := generate(dir, options{Type: "Service", Prefix: "svc", Client: "Remote", Register: "Serve"})
+	require.NoError(t, err)
+	out := string(src)
+
+	assert.Contains(t, out, "package svc\n")
+	assert.Contains(t, out, "import (\n\t\"context\"\n\t\"encoding/json\"\n\t\"fmt\"\n\n"+
+		"\text \"github.com/example/types\"\n\t\"github.com/go-pkgz/jrpc\"\n\t\"gopkg.in/yaml.v3\"\n)")
+	assert.Contains(t, out, "type Remote struct {")
+	assert.Contains(t, out, `func (c *Remote) GetByID(ctx context.Context, id string) (*ext.Item, error) {`)
+	assert.Contains(t, out, "var res *ext.Item\n\tresp, err := c.Client.CallContext(ctx, \"svc.getByID\", id)")
+	assert.Contains(t, out, "if resp.Result == nil {\n\t\treturn res, fmt.Errorf(\"%w for svc.getByID\", jrpc.ErrNoResult)",
+		"only missing result is an error, null one leaves the zero value")
+	assert.Contains(t, out, "var p0 string\n\t\t\tif err := jrpc.DecodeParams(params, &p0); err != nil {",
+		"single param decoded as by Handle, empty and null params skipped")
+	assert.Contains(t, out, `func (c *Remote) Parse(p0 yaml.Node, p1 int, p2 string) error {`)
+	assert.Contains(t, out, `c.Client.Call("svc.parse", p0, p1, p2)`)
+	assert.Contains(t, out, `func Serve(s *jrpc.Server, impl Service) {`)
+	assert.Contains(t, out, `s.GroupContext("svc", jrpc.ContextHandlersGroup{`)
+	assert.Contains(t, out, `return jrpc.EncodeResponse(id, nil, impl.Parse(p0, p1, p2))`)
+}
+
+func TestGenerateFailed(t *testing.T) {
+	tbl := []struct {
+		name, src, err string
+	}{
+		{"not found", "package p\ntype Other interface{ Do() error }", "interface Svc not found in"},
+		{"not interface", "package p\ntype Svc struct{}", "interface Svc not found in"},
+		{"no methods", "package p\ntype Svc interface{}", "interface Svc: no methods"},
+		{"embedded", "package p\nimport \"io\"\ntype Svc interface{ io.Closer }", "interface Svc: embedded io.Closer not supported"},
+		{"generic", "package p\ntype Svc[T any] interface{ Do(T) error }", "interface Svc: generic interfaces not supported"},
+		{"variadic", "package p\ntype Svc interface{ Do(a ...int) error }", "interface Svc: method Do: variadic params not supported"},
+		{"func param", "package p\ntype Svc interface{ Do(f func()) error }", "interface Svc: method Do: func params not supported"},
+		{"no error", "package p\ntype Svc interface{ Do() int }", "interface Svc: method Do: has to return error or a value and error"},
+		{"no results", "package p\ntype Svc interface{ Do() }", "interface Svc: method Do: has to return error or a value and error"},
+		{"many results", "package p\ntype Svc interface{ Do() (int, int, error) }",
+			"interface Svc: method Do: has to return error or a value and error"},
+		{"two contexts", "package p\nimport \"context\"\ntype Svc interface{ Do(a, b context.Context) error }",
+			"interface Svc: method Do: only the first param can be context.Context"},
+		{"broken source", "package p\ntype Svc interface{", "can't parse"},
+	}
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate(writePkg(t, tt.src), options{Type: "Svc"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+
+	_, err := generate(t.TempDir(), options{Type: "Svc"})
+	assert.ErrorContains(t, err, "no go files in")
+}
+
+func TestPkgName(t *testing.T) {
+	tbl := []struct{ in, out string }{
+		{"time", "time"},
+		{"encoding/json", "json"},
+		{"github.com/go-pkgz/rest/v2", "rest"},
+		{"gopkg.in/yaml.v3", "yaml"},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.out, pkgName(tt.in), tt.in)
+	}
+}
+
+// writePkg writes src as the only file of the package in temp dir and returns the dir
+func writePkg(t *testing.T, src string) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "svc.go"), []byte(src), 0o600))
+	return dir
+}
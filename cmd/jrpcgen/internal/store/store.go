@@ -0,0 +1,25 @@
+// Package store is an example of the interface with jrpc client and server adapter generated by jrpcgen
+package store
+
+import (
+	"context"
+	"time"
+)
+
+//go:generate go run github.com/go-pkgz/jrpc/cmd/jrpcgen -type Store
+
+// Record to be stored and retrieved by a remote client
+type Record struct {
+	TS    time.Time
+	Value string
+}
+
+// Store is a toy storage served remotely
+type Store interface {
+	Save(ctx context.Context, rec Record) (string, error)
+	Load(ctx context.Context, id string) (Record, error)
+	Find(ctx context.Context, from, to time.Time) ([]string, error)
+	Delete(ctx context.Context, id string) error
+	Count() (int, error)
+	Ping() error
+}
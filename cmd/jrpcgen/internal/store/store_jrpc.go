@@ -0,0 +1,152 @@
+// Code generated by jrpcgen. DO NOT EDIT.
+
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-pkgz/jrpc"
+)
+
+// StoreClient implements Store by calls to remote jrpc server, methods named "store.*"
+type StoreClient struct {
+	Client *jrpc.Client
+}
+
+var _ Store = (*StoreClient)(nil)
+
+// Save calls remote store.save
+func (c *StoreClient) Save(ctx context.Context, rec Record) (string, error) {
+	var res string
+	resp, err := c.Client.CallContext(ctx, "store.save", rec)
+	if err != nil {
+		return res, err
+	}
+	if resp.Result == nil {
+		return res, fmt.Errorf("%w for store.save", jrpc.ErrNoResult)
+	}
+	if err = json.Unmarshal(*resp.Result, &res); err != nil {
+		return res, fmt.Errorf("failed to decode result for store.save: %w", err)
+	}
+	return res, nil
+}
+
+// Load calls remote store.load
+func (c *StoreClient) Load(ctx context.Context, id string) (Record, error) {
+	var res Record
+	resp, err := c.Client.CallContext(ctx, "store.load", id)
+	if err != nil {
+		return res, err
+	}
+	if resp.Result == nil {
+		return res, fmt.Errorf("%w for store.load", jrpc.ErrNoResult)
+	}
+	if err = json.Unmarshal(*resp.Result, &res); err != nil {
+		return res, fmt.Errorf("failed to decode result for store.load: %w", err)
+	}
+	return res, nil
+}
+
+// Find calls remote store.find
+func (c *StoreClient) Find(ctx context.Context, from time.Time, to time.Time) ([]string, error) {
+	var res []string
+	resp, err := c.Client.CallContext(ctx, "store.find", from, to)
+	if err != nil {
+		return res, err
+	}
+	if resp.Result == nil {
+		return res, fmt.Errorf("%w for store.find", jrpc.ErrNoResult)
+	}
+	if err = json.Unmarshal(*resp.Result, &res); err != nil {
+		return res, fmt.Errorf("failed to decode result for store.find: %w", err)
+	}
+	return res, nil
+}
+
+// Delete calls remote store.delete
+func (c *StoreClient) Delete(ctx context.Context, id string) error {
+	_, err := c.Client.CallContext(ctx, "store.delete", id)
+	return err
+}
+
+// Count calls remote store.count
+func (c *StoreClient) Count() (int, error) {
+	var res int
+	resp, err := c.Client.Call("store.count")
+	if err != nil {
+		return res, err
+	}
+	if resp.Result == nil {
+		return res, fmt.Errorf("%w for store.count", jrpc.ErrNoResult)
+	}
+	if err = json.Unmarshal(*resp.Result, &res); err != nil {
+		return res, fmt.Errorf("failed to decode result for store.count: %w", err)
+	}
+	return res, nil
+}
+
+// Ping calls remote store.ping
+func (c *StoreClient) Ping() error {
+	_, err := c.Client.Call("store.ping")
+	return err
+}
+
+// RegisterStore registers methods of impl with the server as "store" group, i.e. "store.save".
+// Params which can't be decoded reported to the caller as jrpc.Error with jrpc.ErrCodeInvalidParams code.
+func RegisterStore(s *jrpc.Server, impl Store) {
+	s.GroupContext("store", jrpc.ContextHandlersGroup{
+		"save": func(ctx context.Context, id uint64, params json.RawMessage) jrpc.Response {
+			var p0 Record
+			if err := jrpc.DecodeParams(params, &p0); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			res, err := impl.Save(ctx, p0)
+			return jrpc.EncodeResponse(id, res, err)
+		},
+		"load": func(ctx context.Context, id uint64, params json.RawMessage) jrpc.Response {
+			var p0 string
+			if err := jrpc.DecodeParams(params, &p0); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			res, err := impl.Load(ctx, p0)
+			return jrpc.EncodeResponse(id, res, err)
+		},
+		"find": func(ctx context.Context, id uint64, params json.RawMessage) jrpc.Response {
+			var args []json.RawMessage
+			if err := jrpc.DecodeParams(params, &args); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			if len(args) != 2 {
+				return jrpc.EncodeResponse(id, nil, jrpc.NewError(jrpc.ErrCodeInvalidParams,
+					fmt.Sprintf("invalid params: expected 2, got %d", len(args))))
+			}
+			var p0 time.Time
+			if err := jrpc.DecodeParams(args[0], &p0); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			var p1 time.Time
+			if err := jrpc.DecodeParams(args[1], &p1); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			res, err := impl.Find(ctx, p0, p1)
+			return jrpc.EncodeResponse(id, res, err)
+		},
+		"delete": func(ctx context.Context, id uint64, params json.RawMessage) jrpc.Response {
+			var p0 string
+			if err := jrpc.DecodeParams(params, &p0); err != nil {
+				return jrpc.EncodeResponse(id, nil, err)
+			}
+			return jrpc.EncodeResponse(id, nil, impl.Delete(ctx, p0))
+		},
+		"count": func(_ context.Context, id uint64, _ json.RawMessage) jrpc.Response {
+			res, err := impl.Count()
+			return jrpc.EncodeResponse(id, res, err)
+		},
+		"ping": func(_ context.Context, id uint64, _ json.RawMessage) jrpc.Response {
+			return jrpc.EncodeResponse(id, nil, impl.Ping())
+		},
+	})
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/jrpc"
+)
+
+func TestGenerated(t *testing.T) {
+	srv := jrpc.NewServer("/command", jrpc.Auth("user", "passwd"))
+	impl := &memStore{data: map[string]Record{}}
+	RegisterStore(srv, impl)
+	api := run(t, srv)
+
+	c := &StoreClient{Client: &jrpc.Client{API: api, AuthUser: "user", AuthPasswd: "passwd"}}
+	ctx := context.Background()
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	id, err := c.Save(ctx, Record{TS: ts, Value: "v1"})
+	require.NoError(t, err)
+	assert.Equal(t, "1", id)
+	_, err = c.Save(ctx, Record{TS: ts.Add(time.Hour), Value: "v2"})
+	require.NoError(t, err)
+
+	rec, err := c.Load(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, Record{TS: ts, Value: "v1"}, rec)
+
+	ids, err := c.Find(ctx, ts.Add(-time.Minute), ts.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, ids)
+	ids, err = c.Find(ctx, ts.Add(time.Hour), ts)
+	require.NoError(t, err, "nil slice sent as null result")
+	assert.Empty(t, ids)
+
+	require.NoError(t, c.Delete(ctx, id))
+	_, err = c.Load(ctx, id)
+	assert.EqualError(t, err, "not found 1")
+
+	count, err := c.Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, c.Ping())
+	impl.Lock()
+	impl.down = true
+	impl.Unlock()
+	assert.EqualError(t, c.Ping(), "store is down")
+
+	// params of a wrong type reported as invalid params
+	_, err = c.Client.Call("store.load", 123)
+	var rpcErr *jrpc.Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, jrpc.ErrCodeInvalidParams, rpcErr.Code)
+
+	_, err = c.Client.Call("store.find", ts)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, jrpc.ErrCodeInvalidParams, rpcErr.Code)
+
+	_, err = c.Client.Call("store.find", ts, ts, ts)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "invalid params: expected 2, got 3", rpcErr.Message)
+
+	_, err = c.Client.Call("store.find")
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "invalid params: expected 2, got 0", rpcErr.Message)
+}
+
+func TestGeneratedMissingParams(t *testing.T) {
+	srv := jrpc.NewServer("/command")
+	RegisterStore(srv, &memStore{data: map[string]Record{}})
+	c := &StoreClient{Client: &jrpc.Client{API: run(t, srv)}}
+
+	// omitted and null params leave the param with zero value, as for jrpc.Handle
+	resp, err := c.Client.Call("store.save")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"1"`, string(*resp.Result))
+	var rec *Record
+	resp, err = c.Client.Call("store.save", rec)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"2"`, string(*resp.Result))
+
+	_, err = c.Client.Call("store.load")
+	assert.EqualError(t, err, "not found ", "empty id passed to the handler")
+}
+
+func TestGeneratedNoResult(t *testing.T) {
+	srv := jrpc.NewServer("/command")
+	srv.Group("store", jrpc.HandlersGroup{
+		"count": func(id uint64, _ json.RawMessage) jrpc.Response { return jrpc.Response{ID: id} },
+		"load":  func(id uint64, _ json.RawMessage) jrpc.Response { return jrpc.EncodeResponse(id, nil, nil) },
+	})
+	c := &StoreClient{Client: &jrpc.Client{API: run(t, srv)}}
+
+	// missing result reported as jrpc.ErrNoResult
+	_, err := c.Count()
+	require.ErrorIs(t, err, jrpc.ErrNoResult)
+	assert.EqualError(t, err, "no result for store.count")
+
+	// null result leaves the zero value
+	rec, err := c.Load(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, Record{}, rec)
+}
+
+// run starts the server on a random port and returns url of the api
+func run(t *testing.T, srv *jrpc.Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(port) }()
+	t.Cleanup(func() {
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+		assert.NoError(t, srv.Shutdown())
+		assert.ErrorIs(t, <-done, http.ErrServerClosed)
+	})
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+	return fmt.Sprintf("http://127.0.0.1:%d/command", port)
+}
+
+type memStore struct {
+	sync.Mutex
+	data map[string]Record
+	next int
+	down bool
+}
+
+func (m *memStore) Save(_ context.Context, rec Record) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.next++
+	id := fmt.Sprintf("%d", m.next)
+	m.data[id] = rec
+	return id, nil
+}
+
+func (m *memStore) Load(_ context.Context, id string) (Record, error) {
+	m.Lock()
+	defer m.Unlock()
+	rec, ok := m.data[id]
+	if !ok {
+		return Record{}, fmt.Errorf("not found %s", id)
+	}
+	return rec, nil
+}
+
+func (m *memStore) Find(_ context.Context, from, to time.Time) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+	var res []string
+	for id, rec := range m.data {
+		if rec.TS.After(from) && rec.TS.Before(to) {
+			res = append(res, id)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func (m *memStore) Delete(_ context.Context, id string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.data, id)
+	return nil
+}
+
+func (m *memStore) Count() (int, error) {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.data), nil
+}
+
+func (m *memStore) Ping() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.down {
+		return errors.New("store is down")
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Command jrpcgen generates jrpc client and server adapter for a go interface.
+//
+// It reads the interface from go files of the package in the current directory and writes a file with
+// the client implementing the interface by calls to jrpc.Client, and a function registering an implementation
+// of the interface with jrpc.Server as a group. Methods named in the prefix.method form, with the first
+// letter (or the leading acronym) of go method lowered, e.g. Save of Store is "store.save".
+//
+// Supported methods have optional context.Context as the first param, any number of other params and
+// return either error alone or a single value and error. Usage with go generate:
+//
+//	//go:generate go run github.com/go-pkgz/jrpc/cmd/jrpcgen -type Store
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the interface, required")
+	prefix := flag.String("prefix", "", "prefix (group) of methods, lowered interface name by default")
+	output := flag.String("output", "", "output file, <type>_jrpc.go by default")
+	client := flag.String("client", "", "name of the client type, <type>Client by default")
+	register := flag.String("register", "", "name of the register function, Register<type> by default")
+	dir := flag.String("dir", ".", "directory of the package with the interface")
+	flag.Parse()
+
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := options{
+		Type:     *typeName,
+		Prefix:   *prefix,
+		Client:   *client,
+		Register: *register,
+	}
+	src, err := generate(*dir, opts)
+	if err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+
+	out := *output
+	if out == "" {
+		out = filepath.Join(*dir, strings.ToLower(*typeName)+"_jrpc.go")
+	}
+	if err = os.WriteFile(out, src, 0o644); err != nil { //nolint:gosec // generated source is not a secret
+		log.Fatalf("[ERROR] %v", err)
+	}
+	fmt.Printf("generated %s\n", out)
+}
@@ -0,0 +1,40 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// discoveryMethod is the name of built-in method listing all the registered methods
+const discoveryMethod = "rpc.methods"
+
+// MethodInfo describes registered method, as returned by rpc.methods and Server.Methods
+type MethodInfo struct {
+	Name   string  `json:"name"`             // full method name, i.e. "store.save"
+	Params *Schema `json:"params,omitempty"` // json schema of params, for typed handlers only
+	Result *Schema `json:"result,omitempty"` // json schema of result, for typed handlers only
+}
+
+// Methods returns all the registered methods sorted by name. Schemas of params and result reflected
+// from go types of typed handlers, see Handle, and left empty for the rest.
+func (s *Server) Methods() []MethodInfo {
+	s.httpServer.Lock()
+	defer s.httpServer.Unlock()
+	return s.methods()
+}
+
+// methods is Methods without lock, safe to call only for activated server, as no methods added after activation
+func (s *Server) methods() []MethodInfo {
+	res := make([]MethodInfo, 0, len(s.funcs.m))
+	for name, h := range s.funcs.m {
+		res = append(res, MethodInfo{Name: name, Params: schemaOf(h.params), Result: schemaOf(h.result)})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// discoveryHndl is the handler of rpc.methods
+func (s *Server) discoveryHndl(_ context.Context, id uint64, _ json.RawMessage) Response {
+	return EncodeResponse(id, s.methods(), nil)
+}
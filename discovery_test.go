@@ -0,0 +1,60 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerDiscovery(t *testing.T) {
+	type saveReq struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+
+	s := NewServer("/v1/cmd", Auth("user", "passwd"), WithDiscovery())
+	Handle(s, "store.save", func(_ context.Context, p saveReq) (bool, error) { return true, nil })
+	s.Group("store", HandlersGroup{
+		"load": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "value", nil) },
+	})
+
+	// discovery method added on activation only
+	assert.Equal(t, []MethodInfo{
+		{Name: "store.load"},
+		{Name: "store.save", Params: &Schema{Type: "object", Properties: map[string]*Schema{
+			"key": {Type: "string"}, "value": {Type: "string"}}}, Result: &Schema{Type: "boolean"}},
+	}, s.Methods())
+
+	url := startServer(t, s)
+
+	c := &Client{API: url + "/v1/cmd", AuthUser: "user", AuthPasswd: "passwd"}
+	r, err := c.Call("rpc.methods")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"name":"rpc.methods"},
+		{"name":"store.load"},
+		{"name":"store.save","params":{"type":"object","properties":{"key":{"type":"string"},"value":{"type":"string"}}},
+			"result":{"type":"boolean"}}
+	]`, string(*r.Result))
+
+	methods, err := Invoke[[]MethodInfo](context.Background(), c, "rpc.methods")
+	require.NoError(t, err)
+	assert.Len(t, methods, 3)
+
+	// guarded by the same auth
+	_, err = (&Client{API: url + "/v1/cmd"}).Call("rpc.methods")
+	assert.EqualError(t, err, "bad status 401 Unauthorized for rpc.methods")
+}
+
+func TestServerDiscoveryDisabled(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return Response{} })
+	url := startServer(t, s)
+
+	_, err := (&Client{API: url + "/v1/cmd", Client: http.Client{}}).Call("rpc.methods")
+	assert.EqualError(t, err, "bad status 501 Not Implemented for rpc.methods")
+}
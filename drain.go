@@ -0,0 +1,169 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// inflight tracks running calls and the drain state of the server. Zero value is ready to use.
+type inflight struct {
+	mu       sync.Mutex
+	n        int                                // number of running calls, including accepted notifications not processed yet
+	draining bool                               // new calls rejected
+	idle     chan struct{}                      // closed when nothing is running while draining
+	aborted  bool                               // stop forced, handler contexts canceled
+	seq      uint64                             // key of the next tracked handler context
+	cancels  map[uint64]context.CancelCauseFunc // cancel funcs of running handler contexts
+}
+
+// enter registers a new call, returns false if the server is draining and the call has to be rejected
+func (f *inflight) enter() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.draining {
+		return false
+	}
+	f.n++
+	return true
+}
+
+// leave unregisters finished call
+func (f *inflight) leave() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.n--
+	if f.n == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// drain stops accepting new calls, returns channel closed as soon as all running calls finished
+func (f *inflight) drain() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.draining = true
+	ch := make(chan struct{})
+	if f.n == 0 {
+		close(ch)
+		return ch
+	}
+	if f.idle != nil {
+		close(f.idle) // previous drain gave up, nobody waits for it anymore
+	}
+	f.idle = ch
+	return ch
+}
+
+// isDraining reports whether new calls rejected
+func (f *inflight) isDraining() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.draining
+}
+
+// count returns number of running calls
+func (f *inflight) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.n
+}
+
+// track registers cancel func of the handler context to be called by abort, returns func unregistering it.
+// The context canceled right away if the stop forced already.
+func (f *inflight) track(cancel context.CancelCauseFunc) (untrack func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.aborted {
+		cancel(ErrShuttingDown)
+		return func() {}
+	}
+	if f.cancels == nil {
+		f.cancels = map[uint64]context.CancelCauseFunc{}
+	}
+	f.seq++
+	key := f.seq
+	f.cancels[key] = cancel
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.cancels, key)
+	}
+}
+
+// abort cancels contexts of all running handlers with ErrShuttingDown cause. Cancellation is synchronous,
+// so the cause is set before the connections closed and the request contexts canceled.
+func (f *inflight) abort() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.aborted = true
+	for _, cancel := range f.cancels {
+		cancel(ErrShuttingDown)
+	}
+	clear(f.cancels)
+}
+
+// ShutdownContext drains the server gracefully. New calls rejected with ErrShuttingDown right away, while calls
+// in flight, including queued notifications, allowed to finish until ctx is done. After that the handler contexts
+// of the calls still running canceled, connections closed, and ShutdownError with the number of those calls returned.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	if err := srv.ShutdownContext(ctx); err != nil {
+//		var se *jrpc.ShutdownError
+//		if errors.As(err, &se) {
+//			log.Printf("forced stop, %d calls interrupted", se.InFlight)
+//		}
+//	}
+func (s *Server) ShutdownContext(ctx context.Context) error {
+	// the lock is not held while waiting, so handlers still running can call Methods, OpenRPC and so on
+	s.httpServer.Lock()
+	if s.httpServer.handler == nil {
+		s.httpServer.Unlock()
+		return fmt.Errorf("http server is not running")
+	}
+	idle := s.inflight.drain()
+	s.httpServer.Unlock()
+
+	s.logger.Logf("[INFO] shutdown, draining %d calls", s.inflight.count())
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		n := s.inflight.count()
+		s.logger.Logf("[WARN] shutdown forced, %d calls in flight canceled", n)
+		s.inflight.abort()
+		s.httpServer.Lock()
+		s.closeHTTP()
+		s.httpServer.Unlock()
+		if s.notifications.queue != nil {
+			_ = s.notifications.queue.close(ctx) // stops accepting, ctx is done already so doesn't wait
+		}
+		return &ShutdownError{InFlight: n, Err: ctx.Err()}
+	}
+
+	s.httpServer.Lock()
+	defer s.httpServer.Unlock()
+	if s.httpServer.Server != nil {
+		if err := s.httpServer.Shutdown(ctx); err != nil {
+			s.closeHTTP()
+			return err
+		}
+	}
+	if s.notifications.queue != nil {
+		return s.notifications.queue.close(ctx) // all the queued notifications done already, stops workers
+	}
+	return nil
+}
+
+// closeHTTP closes listeners and all connections of the http server, called with httpServer locked
+func (s *Server) closeHTTP() {
+	if s.httpServer.Server == nil {
+		return
+	}
+	if err := s.httpServer.Close(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Logf("[WARN] can't close http server: %v", err)
+	}
+}
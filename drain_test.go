@@ -0,0 +1,212 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerShutdownContextDrain(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s := NewServer("/v1/cmd")
+	s.Add("store.save", func(id uint64, _ json.RawMessage) Response {
+		close(started)
+		<-release
+		return EncodeResponse(id, "saved", nil)
+	})
+	s.Add("store.get", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "got", nil) })
+
+	l := listen(t)
+	s.activate()
+	served := make(chan error, 1)
+	go func() { served <- s.serve(l) }()
+	c := Client{API: "http://" + l.Addr().String() + "/v1/cmd", Client: http.Client{Transport: &http.Transport{}}}
+
+	saved := make(chan error, 1)
+	go func() {
+		resp, err := c.Call("store.save")
+		if err == nil {
+			var res string
+			err = json.Unmarshal(*resp.Result, &res)
+			assert.Equal(t, "saved", res)
+		}
+		saved <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- s.ShutdownContext(ctx)
+	}()
+	require.Eventually(t, s.inflight.isDraining, time.Second, 5*time.Millisecond)
+
+	_, err := c.Call("store.get")
+	require.ErrorIs(t, err, ErrShuttingDown, "new calls rejected while draining")
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, ErrCodeShuttingDown, rpcErr.Code)
+
+	select {
+	case err = <-shutdown:
+		t.Fatalf("shutdown returned before the call finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-saved, "call in flight finished")
+	require.NoError(t, <-shutdown)
+	assert.ErrorIs(t, <-served, http.ErrServerClosed)
+	assert.Equal(t, 0, s.inflight.count())
+}
+
+func TestServerShutdownContextForced(t *testing.T) {
+	started, causes := make(chan struct{}), make(chan error, 1)
+	s := NewServer("/v1/cmd")
+	s.AddContext("store.save", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		close(started)
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return EncodeResponse(id, nil, ctx.Err())
+	})
+
+	l := listen(t)
+	s.activate()
+	served := make(chan error, 1)
+	go func() { served <- s.serve(l) }()
+	c := Client{API: "http://" + l.Addr().String() + "/v1/cmd", Client: http.Client{Transport: &http.Transport{}}}
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := c.Call("store.save")
+		called <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.ShutdownContext(ctx)
+	var se *ShutdownError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, 1, se.InFlight)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.EqualError(t, err, "shutdown forced with 1 calls in flight: context deadline exceeded")
+
+	assert.ErrorIs(t, <-causes, ErrShuttingDown, "handler context canceled on forced stop")
+	assert.Error(t, <-called, "connection closed")
+	assert.ErrorIs(t, <-served, http.ErrServerClosed)
+}
+
+func TestServerShutdownContextNotifications(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan string, 3)
+	s := NewServer("/v1/cmd", WithJSONRPC2(), WithNotifyQueue(10, 1))
+	s.Add("store.save", func(_ uint64, params json.RawMessage) Response {
+		<-release
+		var p string
+		_ = DecodeParams(params, &p)
+		done <- p
+		return Response{}
+	})
+	h := s.Handler() // handler only mode, no http server to shut down
+
+	c := Client{API: "http://local/v1/cmd", JSONRPC2: true, Client: http.Client{Transport: handlerTransport{h}}}
+	require.NoError(t, c.Notify("store.save", "a"))
+	require.NoError(t, c.Notify("store.save", "b"))
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- s.ShutdownContext(ctx)
+	}()
+	require.Eventually(t, s.inflight.isDraining, time.Second, 5*time.Millisecond)
+	require.NoError(t, c.Notify("store.save", "c"), "rejected notification has no response")
+	assert.Equal(t, 2, s.inflight.count(), "queued notifications are in flight")
+
+	close(release)
+	require.NoError(t, <-shutdown)
+	assert.Equal(t, "a", <-done)
+	assert.Equal(t, "b", <-done)
+	assert.Empty(t, done, "notification sent while draining dropped")
+}
+
+func TestServerShutdownContextHandlerUsesServer(t *testing.T) {
+	started, drained := make(chan struct{}), make(chan struct{})
+	s := NewServer("/v1/cmd")
+	s.Add("store.info", func(id uint64, _ json.RawMessage) Response {
+		close(started)
+		<-drained
+		// server methods taking the lock don't block while the shutdown waits for this call
+		s.AddCheck("late", func(context.Context) error { return nil })
+		return EncodeResponse(id, len(s.Methods())+len(s.OpenRPC().Methods), nil)
+	})
+	h := s.Handler()
+
+	c := Client{API: "http://local/v1/cmd", Client: http.Client{Transport: handlerTransport{h}}}
+	called := make(chan error, 1)
+	go func() {
+		_, err := c.Call("store.info")
+		called <- err
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		shutdown <- s.ShutdownContext(ctx)
+	}()
+	require.Eventually(t, s.inflight.isDraining, time.Second, 5*time.Millisecond)
+	close(drained)
+
+	require.NoError(t, <-called)
+	require.NoError(t, <-shutdown, "drained without forcing")
+}
+
+func TestInflight(t *testing.T) {
+	var f inflight
+	require.True(t, f.enter())
+	require.True(t, f.enter())
+	assert.Equal(t, 2, f.count())
+
+	idle := f.drain()
+	assert.False(t, f.enter(), "rejected while draining")
+	f.leave()
+	select {
+	case <-idle:
+		t.Fatal("idle with a call running")
+	default:
+	}
+	f.leave()
+	<-idle
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	untrack := f.track(cancel)
+	done, cancelDone := context.WithCancelCause(context.Background())
+	f.track(cancelDone)
+	untrack()
+	f.abort()
+	assert.NoError(t, ctx.Err(), "untracked context not canceled")
+	assert.ErrorIs(t, context.Cause(done), ErrShuttingDown)
+
+	late, cancelLate := context.WithCancelCause(context.Background())
+	f.track(cancelLate)
+	assert.ErrorIs(t, context.Cause(late), ErrShuttingDown, "canceled right away after abort")
+}
+
+// handlerTransport serves client requests with the handler directly
+type handlerTransport struct{ h http.Handler }
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	w := httptest.NewRecorder()
+	t.h.ServeHTTP(w, r)
+	return w.Result(), nil
+}
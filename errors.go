@@ -0,0 +1,99 @@
+package jrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// predefined error codes, match the codes of json-rpc 2.0 spec
+const (
+	ErrCodeParse          = -32700 // request is not a valid json, used in json-rpc 2.0 mode only
+	ErrCodeInvalidRequest = -32600 // request is a valid json but not a valid request, i.e. a broken call of the batch
+	ErrCodeMethodNotFound = -32601 // method not registered on the server
+	ErrCodeInvalidParams  = -32602 // params can't be decoded to the type expected by the handler
+	ErrCodeInternal       = -32603 // server failed to make the response, i.e. result can't be encoded
+	ErrCodeTimeout        = -32000 // call not finished within CallTimeout
+	ErrCodeForbidden      = -32001 // caller not allowed to call the method, see WithAuthorizer
+	ErrCodeShuttingDown   = -32002 // call rejected, the server is draining, see Server.ShutdownContext
+)
+
+// ErrInvalidParams returned to the caller if params can't be decoded to the type expected by the handler.
+// Matches with errors.Is any Error with ErrCodeInvalidParams code.
+var ErrInvalidParams = &Error{Code: ErrCodeInvalidParams, Message: "invalid params"}
+
+// ErrForbidden returned to the caller not allowed to call the method, see WithAuthorizer.
+// Matches with errors.Is any Error with ErrCodeForbidden code.
+var ErrForbidden = &Error{Code: ErrCodeForbidden, Message: "forbidden"}
+
+// ErrShuttingDown returned to the caller of a new call while the server is draining, safe to retry
+// with another instance. Matches with errors.Is any Error with ErrCodeShuttingDown code.
+var ErrShuttingDown = &Error{Code: ErrCodeShuttingDown, Message: "server is shutting down"}
+
+// Error is a structured rpc error with code, message and optional data. Handlers return it
+// as the error of EncodeResponse, and the client gives it back as the error of Call,
+// so the caller can get it with errors.As and check the code.
+type Error struct {
+	Code    int             `json:"code"`           // error code, application defined or one of ErrCode* constants
+	Message string          `json:"message"`        // error message, the same as plain error string
+	Data    json.RawMessage `json:"data,omitempty"` // optional error details, any json
+}
+
+// NewError makes Error with given code and message
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Error returns message only, so Error reads the same as a plain error string
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is Error with the same non-zero code
+func (e *Error) Is(target error) bool {
+	var t *Error
+	if !errors.As(target, &t) {
+		return false
+	}
+	return t.Code != 0 && t.Code == e.Code
+}
+
+// StatusError returned by the client if the server responded with non-2xx http status. If the response
+// body carries a coded error, i.e. ErrCodeMethodNotFound for unknown method or ErrCodeTimeout for the call
+// aborted by CallTimeout, it is available as Err, and with errors.As on the StatusError itself.
+type StatusError struct {
+	StatusCode int    // http status code, i.e. 503
+	Status     string // http status line, i.e. "503 Service Unavailable"
+	Method     string // rpc method of the call
+	Err        *Error // coded error of the response body, nil if the body has none
+}
+
+// Error returns status and method of the call
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("bad status %s for %s", e.Status, e.Method)
+}
+
+// Unwrap returns the coded error of the response body, if any
+func (e *StatusError) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// ShutdownError returned by Server.ShutdownContext if calls were still running when the context was done.
+// The handler contexts of those calls canceled, and the connections closed without waiting for them.
+type ShutdownError struct {
+	InFlight int   // number of calls still running when the stop forced
+	Err      error // error of the context, i.e. context.DeadlineExceeded
+}
+
+// Error returns the number of calls in flight and the context error
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown forced with %d calls in flight: %v", e.InFlight, e.Err)
+}
+
+// Unwrap returns the context error
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
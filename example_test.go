@@ -1,6 +1,7 @@
 package jrpc_test
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"time"
@@ -46,6 +47,21 @@ func ExampleNewServer_options() {
 	_ = plugin.Run(8080)
 }
 
+// ExampleHandle shows the typed handler, with params decoded and the result encoded by jrpc.
+func ExampleHandle() {
+	plugin := jrpc.NewServer("/command")
+
+	type greeting struct {
+		Name string
+	}
+
+	jrpc.Handle(plugin, "greet", func(_ context.Context, p greeting) (string, error) {
+		return "hello, " + p.Name, nil
+	})
+
+	_ = plugin.Run(8080)
+}
+
 // ExampleClient_Call shows how an application calls the remote method.
 func ExampleClient_Call() {
 	rpcClient := jrpc.Client{
@@ -8,11 +8,11 @@ require (
 	github.com/go-pkgz/rest v1.24.0
 	github.com/go-pkgz/routegroup v1.6.1
 	github.com/stretchr/testify v1.12.0
+	golang.org/x/crypto v0.48.0
 )
 
 require (
 	github.com/kr/pretty v0.3.1 // indirect
-	golang.org/x/crypto v0.48.0 // indirect
 	golang.org/x/sys v0.41.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
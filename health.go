@@ -0,0 +1,55 @@
+package jrpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-pkgz/rest"
+)
+
+// CheckFn is a named health check, returns error if the dependency is not healthy, i.e. a db ping
+// or a call to the upstream plugin
+type CheckFn func(ctx context.Context) error
+
+// namedCheck is CheckFn with its name, reported in the health response
+type namedCheck struct {
+	name string
+	fn   CheckFn
+}
+
+// AddCheck adds named check to /health and /ready endpoints enabled by WithHealthChecks. Checks run
+// on each request to the endpoints, with its context, so have to be cheap and respect the context.
+// Like methods, checks have to be added before the server started, or Handler called.
+//
+//	srv.AddCheck("upstream", func(ctx context.Context) error {
+//		_, err := upstream.CallContext(ctx, "store.ping") // upstream is *jrpc.Client of another plugin
+//		return err
+//	})
+func (s *Server) AddCheck(name string, check CheckFn) {
+	s.httpServer.Lock()
+	defer s.httpServer.Unlock()
+	if s.httpServer.handler != nil {
+		s.logger.Logf("[WARN] ignored check %s, checks can't be added after the server started", name)
+		return
+	}
+	s.health.checks = append(s.health.checks, namedCheck{name: name, fn: check})
+}
+
+// healthMiddleware serves GET /health with all the checks and GET /ready with the checks and the serving state,
+// failing as soon as the server started to drain. Both respond with json list of the checks, 503 if any failed.
+func (s *Server) healthMiddleware() func(http.Handler) http.Handler {
+	checkers := make([]func(ctx context.Context) (string, error), 0, len(s.health.checks)+1)
+	for _, c := range s.health.checks {
+		checkers = append(checkers, func(ctx context.Context) (string, error) { return c.name, c.fn(ctx) })
+	}
+	serving := func(context.Context) (string, error) {
+		if s.inflight.isDraining() {
+			return "serving", ErrShuttingDown
+		}
+		return "serving", nil
+	}
+	health, ready := rest.Health("/health", checkers...), rest.Health("/ready", append(checkers, serving)...)
+	return func(next http.Handler) http.Handler {
+		return health(ready(next))
+	}
+}
@@ -0,0 +1,104 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHealthChecks(t *testing.T) {
+	var dbErr error
+	s := NewServer("/v1/cmd", WithHealthChecks(), Auth("user", "passwd"))
+	s.Add("store.save", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "saved", nil) })
+	s.AddCheck("db", func(context.Context) error { return dbErr })
+	s.AddCheck("upstream", func(ctx context.Context) error { return ctx.Err() })
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("healthy, no credentials needed", func(t *testing.T) {
+		code, body := get("/health")
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `[{"name":"db","status":"ok"},{"name":"upstream","status":"ok"}]`, body)
+
+		code, body = get("/ready")
+		assert.Equal(t, http.StatusOK, code)
+		assert.JSONEq(t, `[{"name":"db","status":"ok"},{"name":"upstream","status":"ok"},
+			{"name":"serving","status":"ok"}]`, body)
+	})
+
+	t.Run("failed check", func(t *testing.T) {
+		dbErr = errors.New("connection refused")
+		defer func() { dbErr = nil }()
+
+		code, body := get("/health")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.JSONEq(t, `[{"name":"db","status":"failed","error":"connection refused"},
+			{"name":"upstream","status":"ok"}]`, body)
+
+		code, _ = get("/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+	})
+
+	t.Run("calls still need auth", func(t *testing.T) {
+		_, err := (&Client{API: ts.URL + "/v1/cmd"}).Call("store.save")
+		var se *StatusError
+		require.ErrorAs(t, err, &se)
+		assert.Equal(t, http.StatusUnauthorized, se.StatusCode)
+	})
+
+	t.Run("not ready while draining", func(t *testing.T) {
+		require.NoError(t, s.Shutdown())
+
+		code, body := get("/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.JSONEq(t, `[{"name":"db","status":"ok"},{"name":"upstream","status":"ok"},
+			{"name":"serving","status":"failed","error":"server is shutting down"}]`, body)
+
+		code, _ = get("/health")
+		assert.Equal(t, http.StatusOK, code, "still healthy, just not ready")
+	})
+}
+
+func TestServerHealthChecksDisabled(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	s.Add("store.save", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "saved", nil) })
+	s.AddCheck("db", func(context.Context) error { return nil })
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	for _, path := range []string{"/health", "/ready"} {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		assert.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+}
+
+func TestServerAddCheckLate(t *testing.T) {
+	s := NewServer("/v1/cmd", WithHealthChecks())
+	s.Add("store.save", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "saved", nil) })
+	h := s.Handler()
+	s.AddCheck("db", func(context.Context) error { return errors.New("down") })
+
+	req := httptest.NewRequest(http.MethodGet, "/health", http.NoBody)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String(), "check added after the handler made ignored")
+}
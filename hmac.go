@@ -0,0 +1,143 @@
+package jrpc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// headers of signed requests
+const (
+	headerKeyID     = "X-Jrpc-Key"
+	headerMethod    = "X-Jrpc-Method"
+	headerTimestamp = "X-Jrpc-Timestamp"
+	headerNonce     = "X-Jrpc-Nonce"
+	headerSignature = "X-Jrpc-Signature"
+)
+
+// HMACKey is the shared key signing requests of Client, has to match one of the keys passed to WithHMAC.
+// Each request signed with HMAC-SHA256 of the method, timestamp, random nonce and sha256 of the body.
+type HMACKey struct {
+	ID     string // key id, the principal of signed calls on the server side
+	Secret []byte // shared secret
+}
+
+// sign adds signature headers to the request with body
+func (k *HMACKey) sign(req *http.Request, method string, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("can't make nonce: %w", err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(headerKeyID, k.ID)
+	req.Header.Set(headerMethod, method)
+	req.Header.Set(headerTimestamp, ts)
+	req.Header.Set(headerNonce, hex.EncodeToString(nonce))
+	req.Header.Set(headerSignature, signature(k.Secret, method, ts, hex.EncodeToString(nonce), body))
+	return nil
+}
+
+// signature makes hex encoded HMAC-SHA256 of the method, timestamp, nonce and sha256 of the body
+func signature(secret []byte, method, ts, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%s\n%x", method, ts, nonce, bodyHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hmacVerifier checks signatures of requests, rejecting stale timestamps and nonces seen within the window
+type hmacVerifier struct {
+	keys   map[string][]byte
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time // seen nonces with expiration time
+	lastSweep time.Time
+}
+
+func newHMACVerifier(keys map[string][]byte, window time.Duration) *hmacVerifier {
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+	return &hmacVerifier{keys: keys, window: window, now: time.Now, nonces: map[string]time.Time{}}
+}
+
+// middleware rejects requests without valid signature with 401. The key id of signed request
+// put to the context as the principal, unless already set by the authenticator.
+func (v *hmacVerifier) middleware(logger L) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "can't read request", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			keyID, err := v.verify(r, body)
+			if err != nil {
+				logger.Logf("[WARN] rejected request from %s: %v", r.RemoteAddr, err)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if PrincipalFromContext(r.Context()) == "" {
+				r = r.WithContext(ContextWithPrincipal(r.Context(), keyID))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// verify checks signature, timestamp and nonce of the request, returns the key id
+func (v *hmacVerifier) verify(r *http.Request, body []byte) (string, error) {
+	keyID, ts, nonce := r.Header.Get(headerKeyID), r.Header.Get(headerTimestamp), r.Header.Get(headerNonce)
+	secret, ok := v.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("unknown key %q", keyID)
+	}
+
+	expected := signature(secret, r.Header.Get(headerMethod), ts, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(headerSignature))) {
+		return "", errors.New("bad signature")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("bad timestamp %q", ts)
+	}
+	now := v.now()
+	if d := now.Sub(time.Unix(unix, 0)).Abs(); d > v.window {
+		return "", fmt.Errorf("stale timestamp %s", time.Unix(unix, 0).UTC().Format(time.RFC3339))
+	}
+	if nonce == "" {
+		return "", errors.New("no nonce")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if now.Sub(v.lastSweep) > v.window {
+		for n, exp := range v.nonces {
+			if now.After(exp) {
+				delete(v.nonces, n)
+			}
+		}
+		v.lastSweep = now
+	}
+	key := keyID + ":" + nonce
+	if exp, seen := v.nonces[key]; seen && now.Before(exp) {
+		return "", fmt.Errorf("replayed nonce %s", nonce)
+	}
+	// timestamp can be up to window in the future, the nonce kept until the timestamp is stale
+	v.nonces[key] = time.Unix(unix, 0).Add(v.window)
+	return keyID, nil
+}
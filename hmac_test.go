@@ -0,0 +1,126 @@
+package jrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHMAC(t *testing.T) {
+	s := NewServer("/v1/cmd", WithHMAC(map[string][]byte{"app": []byte("secret1"), "ops": []byte("secret2")}, time.Minute))
+	s.AddContext("whoami", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, PrincipalFromContext(ctx), nil)
+	})
+	url := startServer(t, s)
+	ctx := context.Background()
+
+	c := &Client{API: url + "/v1/cmd", Sign: &HMACKey{ID: "app", Secret: []byte("secret1")}}
+	for range 3 { // each call has own nonce
+		principal, err := Invoke[string](ctx, c, "whoami")
+		require.NoError(t, err)
+		assert.Equal(t, "app", principal)
+	}
+	resps, err := c.CallBatch(ctx, BatchCall{Method: "whoami"}, BatchCall{Method: "whoami"})
+	require.NoError(t, err)
+	assert.Len(t, resps, 2)
+	require.NoError(t, c.Notify("whoami"))
+
+	principal, err := Invoke[string](ctx, &Client{API: url + "/v1/cmd", Sign: &HMACKey{ID: "ops", Secret: []byte("secret2")}}, "whoami")
+	require.NoError(t, err)
+	assert.Equal(t, "ops", principal)
+
+	for _, c := range []*Client{
+		{API: url + "/v1/cmd"},
+		{API: url + "/v1/cmd", Sign: &HMACKey{ID: "app", Secret: []byte("secret2")}},
+		{API: url + "/v1/cmd", Sign: &HMACKey{ID: "other", Secret: []byte("secret1")}},
+	} {
+		_, err := c.Call("whoami")
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+	}
+}
+
+func TestServerHMACWithAuthenticator(t *testing.T) {
+	s := NewServer("/v1/cmd", WithAuthenticator(BearerTokens{"token": "svc"}),
+		WithHMAC(map[string][]byte{"app": []byte("secret")}, 0))
+	s.AddContext("whoami", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, PrincipalFromContext(ctx), nil)
+	})
+	url := startServer(t, s)
+
+	// both required, principal set by the authenticator
+	c := &Client{API: url + "/v1/cmd", AuthToken: "token", Sign: &HMACKey{ID: "app", Secret: []byte("secret")}}
+	principal, err := Invoke[string](context.Background(), c, "whoami")
+	require.NoError(t, err)
+	assert.Equal(t, "svc", principal)
+
+	c.Sign = nil
+	_, err = c.Call("whoami")
+	assert.EqualError(t, err, "bad status 401 Unauthorized for whoami")
+}
+
+func TestHMACVerifier(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	v := newHMACVerifier(map[string][]byte{"app": []byte("secret")}, time.Minute)
+	v.now = func() time.Time { return now }
+	body := []byte(`{"method":"fn","id":1}`)
+
+	req := func(ts time.Time, nonce string, mutate func(r *http.Request)) *http.Request {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		unix := strconv.FormatInt(ts.Unix(), 10)
+		r.Header.Set(headerKeyID, "app")
+		r.Header.Set(headerMethod, "fn")
+		r.Header.Set(headerTimestamp, unix)
+		r.Header.Set(headerNonce, nonce)
+		r.Header.Set(headerSignature, signature([]byte("secret"), "fn", unix, nonce, body))
+		if mutate != nil {
+			mutate(r)
+		}
+		return r
+	}
+
+	keyID, err := v.verify(req(now, "n1", nil), body)
+	require.NoError(t, err)
+	assert.Equal(t, "app", keyID)
+
+	_, err = v.verify(req(now.Add(time.Second), "n1", nil), body)
+	assert.EqualError(t, err, "replayed nonce n1")
+
+	_, err = v.verify(req(now.Add(-30*time.Second), "n2", nil), body)
+	require.NoError(t, err)
+	_, err = v.verify(req(now.Add(30*time.Second), "n3", nil), body)
+	require.NoError(t, err)
+
+	_, err = v.verify(req(now.Add(-2*time.Minute), "n4", nil), body)
+	assert.EqualError(t, err, "stale timestamp 2024-05-01T09:58:00Z")
+	_, err = v.verify(req(now.Add(2*time.Minute), "n4", nil), body)
+	assert.EqualError(t, err, "stale timestamp 2024-05-01T10:02:00Z")
+
+	_, err = v.verify(req(now, "n5", nil), []byte(`{"method":"other","id":1}`))
+	assert.EqualError(t, err, "bad signature")
+	_, err = v.verify(req(now, "n5", func(r *http.Request) { r.Header.Set(headerMethod, "other") }), body)
+	assert.EqualError(t, err, "bad signature")
+	_, err = v.verify(req(now, "n5", func(r *http.Request) { r.Header.Set(headerNonce, "n6") }), body)
+	assert.EqualError(t, err, "bad signature")
+	_, err = v.verify(req(now, "n5", func(r *http.Request) { r.Header.Set(headerKeyID, "bad") }), body)
+	assert.EqualError(t, err, `unknown key "bad"`)
+	_, err = v.verify(req(now, "", nil), body)
+	assert.EqualError(t, err, "no nonce")
+
+	// expired nonces swept, n1 stale anyway
+	now = now.Add(3 * time.Minute)
+	_, err = v.verify(req(now, "n7", nil), body)
+	require.NoError(t, err)
+	assert.Len(t, v.nonces, 1)
+	_, err = v.verify(req(now.Add(-3*time.Minute), "n1", nil), body)
+	assert.EqualError(t, err, "stale timestamp 2024-05-01T10:00:00Z")
+}
@@ -0,0 +1,101 @@
+package jrpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Interceptor wraps the call of a method at rpc level. Unlike http middlewares, see WithMiddlewares,
+// it gets the call already decoded, with id and params, and the method name available from the context,
+// see MethodFromContext. Interceptor can inspect and change the call, the response, or respond without
+// calling next at all, i.e. to reject the call with Error.
+type Interceptor func(next ContextServerFn) ContextServerFn
+
+// scopedInterceptor is the interceptor with the pattern of methods it applies to, empty pattern for all methods
+type scopedInterceptor struct {
+	pattern string
+	ic      Interceptor
+}
+
+type methodCtxKey struct{}
+
+// MethodFromContext returns the name of the called method, available for interceptors and handlers.
+// Returns empty string if ctx is not the context of a call.
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodCtxKey{}).(string)
+	return method
+}
+
+// Intercept adds interceptors for methods matching the pattern, either the exact method name, i.e. "store.save",
+// or the prefix of a group with ".*", i.e. "store.*". Interceptors applied in the order of specificity, the global
+// ones set with WithInterceptors first, then the group ones and the method ones last, right before the handler.
+// Within the same scope they run in the order added. Ignored if the server already activated.
+func (s *Server) Intercept(pattern string, ics ...Interceptor) {
+	s.httpServer.Lock()
+	defer s.httpServer.Unlock()
+	if s.httpServer.handler != nil {
+		s.logger.Logf("[WARN] ignored interceptors for %s, can't be added to activated server", pattern)
+		return
+	}
+	for _, ic := range ics {
+		s.interceptors = append(s.interceptors, scopedInterceptor{pattern: pattern, ic: ic})
+	}
+}
+
+// intercept wraps handlers of all the methods with matching interceptors, called on activation.
+// Authorization, if enabled, runs after the global interceptors, so they see denied calls too.
+func (s *Server) intercept() {
+	if len(s.interceptors) == 0 && s.authorizer == nil {
+		return
+	}
+	for method, h := range s.funcs.m {
+		var global, group, exact []Interceptor
+		for _, si := range s.interceptors {
+			switch {
+			case si.pattern == "":
+				global = append(global, si.ic)
+			case strings.HasSuffix(si.pattern, ".*") && strings.HasPrefix(method, strings.TrimSuffix(si.pattern, "*")):
+				group = append(group, si.ic)
+			case si.pattern == method:
+				exact = append(exact, si.ic)
+			}
+		}
+		if s.authorizer != nil {
+			global = append(global, s.authorize)
+		}
+		chain := append(append(global, group...), exact...)
+		for i := len(chain) - 1; i >= 0; i-- {
+			h.fn = chain[i](h.fn)
+		}
+		s.funcs.m[method] = h
+	}
+}
+
+// CallFn makes the remote call of the method with args, see Client.CallContext.
+// For notifications the response is always nil.
+type CallFn func(ctx context.Context, method string, args []any) (*Response, error)
+
+// ClientInterceptor wraps the call made by Client, with the method, args, the response and the error available.
+// Interceptor can change any of them or respond without calling next at all. Interceptors applied to calls
+// and notifications, but not to batches, see Client.Interceptors.
+type ClientInterceptor func(next CallFn) CallFn
+
+type headersCtxKey struct{}
+
+// WithRequestHeader returns a copy of ctx with http header added to the requests made by Client with this ctx,
+// i.e. for tracing headers set by an interceptor. Headers added on top of the ones already in ctx.
+func WithRequestHeader(ctx context.Context, key, value string) context.Context {
+	headers := headersFromContext(ctx).Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Add(key, value)
+	return context.WithValue(ctx, headersCtxKey{}, headers)
+}
+
+// headersFromContext returns http headers added with WithRequestHeader, nil if none
+func headersFromContext(ctx context.Context) http.Header {
+	headers, _ := ctx.Value(headersCtxKey{}).(http.Header)
+	return headers
+}
@@ -0,0 +1,205 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerInterceptors(t *testing.T) {
+	var mu sync.Mutex
+	var trace []string
+	record := func(name string) Interceptor {
+		return func(next ContextServerFn) ContextServerFn {
+			return func(ctx context.Context, id uint64, params json.RawMessage) Response {
+				mu.Lock()
+				trace = append(trace, fmt.Sprintf("%s:%s:%d:%s", name, MethodFromContext(ctx), id, params))
+				mu.Unlock()
+				return next(ctx, id, params)
+			}
+		}
+	}
+
+	s := NewServer("/v1/cmd", WithInterceptors(record("g1"), record("g2")))
+	s.Group("store", HandlersGroup{
+		"save": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "saved", nil) },
+		"load": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "loaded", nil) },
+	})
+	s.AddContext("other", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, MethodFromContext(ctx), nil)
+	})
+	s.Intercept("store.save", record("m1"))
+	s.Intercept("store.*", record("s1"))
+	s.Intercept("stor.*", record("never"))
+	url := startServer(t, s)
+
+	c := &Client{API: url + "/v1/cmd"}
+	_, err := c.Call("store.save", "v")
+	require.NoError(t, err)
+	_, err = c.Call("store.load")
+	require.NoError(t, err)
+	resp, err := c.Call("other")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"other"`, string(*resp.Result), "method available for handlers too")
+
+	assert.Equal(t, []string{
+		`g1:store.save:1:"v"`, `g2:store.save:1:"v"`, `s1:store.save:1:"v"`, `m1:store.save:1:"v"`,
+		`g1:store.load:2:`, `g2:store.load:2:`, `s1:store.load:2:`,
+		`g1:other:3:`, `g2:other:3:`,
+	}, trace)
+}
+
+func TestServerInterceptorReject(t *testing.T) {
+	readOnly := func(next ContextServerFn) ContextServerFn {
+		return func(ctx context.Context, id uint64, params json.RawMessage) Response {
+			if MethodFromContext(ctx) != "store.load" {
+				return EncodeResponse(id, nil, NewError(403, "read only"))
+			}
+			return next(ctx, id, params)
+		}
+	}
+	called := false
+	s := NewServer("/v1/cmd")
+	s.Group("store", HandlersGroup{
+		"save": func(id uint64, _ json.RawMessage) Response { called = true; return EncodeResponse(id, "saved", nil) },
+		"load": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "loaded", nil) },
+	})
+	s.Intercept("store.*", readOnly)
+	url := startServer(t, s)
+
+	c := &Client{API: url + "/v1/cmd"}
+	_, err := c.Call("store.save", "v")
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, &Error{Code: 403, Message: "read only"}, rpcErr)
+	assert.False(t, called)
+
+	v, err := Invoke[string](context.Background(), c, "store.load")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+
+	// interceptors can't be added once activated
+	s.Intercept("store.load", readOnly)
+	v, err = Invoke[string](context.Background(), c, "store.load")
+	require.NoError(t, err)
+	assert.Equal(t, "loaded", v)
+}
+
+func TestServerInterceptorBatchAndNotify(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	ic := func(next ContextServerFn) ContextServerFn {
+		return func(ctx context.Context, id uint64, params json.RawMessage) Response {
+			mu.Lock()
+			methods = append(methods, MethodFromContext(ctx))
+			mu.Unlock()
+			return next(ctx, id, params)
+		}
+	}
+	s := NewServer("/v1/cmd", WithInterceptors(ic))
+	s.Add("a", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "a", nil) })
+	s.Add("b", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "b", nil) })
+	url := startServer(t, s)
+
+	c := &Client{API: url + "/v1/cmd"}
+	_, err := c.CallBatch(context.Background(), BatchCall{Method: "a"}, BatchCall{Method: "b"})
+	require.NoError(t, err)
+	require.NoError(t, c.Notify("a"))
+	assert.Equal(t, []string{"a", "b", "a"}, methods)
+}
+
+func TestMethodFromContext(t *testing.T) {
+	assert.Empty(t, MethodFromContext(context.Background()))
+}
+
+func TestClientInterceptors(t *testing.T) {
+	var headers []http.Header
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Clone())
+		mu.Unlock()
+		req := Request{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.ID == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if req.Method == "fail" {
+			_, _ = fmt.Fprintf(w, `{"error":"failed","error_code":42,"id":%d}`, req.ID)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"result":%q,"id":%d}`, req.Method, req.ID)
+	}))
+	defer ts.Close()
+
+	var trace []string
+	record := func(name string) ClientInterceptor {
+		return func(next CallFn) CallFn {
+			return func(ctx context.Context, method string, args []any) (*Response, error) {
+				trace = append(trace, fmt.Sprintf("%s>%s%v", name, method, args))
+				resp, err := next(WithRequestHeader(ctx, "X-Trace", name), method, args)
+				res := "-"
+				if resp != nil {
+					res = string(*resp.Result)
+				}
+				trace = append(trace, fmt.Sprintf("%s<%s:%s:%v", name, method, res, err))
+				return resp, err
+			}
+		}
+	}
+
+	c := &Client{API: ts.URL, Interceptors: []ClientInterceptor{record("i1"), record("i2")}}
+	_, err := c.Call("fn", 1, 2)
+	require.NoError(t, err)
+	v, err := Invoke[string](context.Background(), c, "typed")
+	require.NoError(t, err)
+	assert.Equal(t, "typed", v)
+	_, err = c.Call("fail")
+	assert.EqualError(t, err, "failed")
+	require.NoError(t, c.Notify("event", "a"))
+
+	assert.Equal(t, []string{
+		"i1>fn[1 2]", "i2>fn[1 2]", `i2<fn:"fn":<nil>`, `i1<fn:"fn":<nil>`,
+		"i1>typed[]", "i2>typed[]", `i2<typed:"typed":<nil>`, `i1<typed:"typed":<nil>`,
+		"i1>fail[]", "i2>fail[]", "i2<fail:-:failed", "i1<fail:-:failed",
+		"i1>event[a]", "i2>event[a]", "i2<event:-:<nil>", "i1<event:-:<nil>",
+	}, trace)
+
+	require.Len(t, headers, 4)
+	for _, h := range headers {
+		assert.Equal(t, []string{"i1", "i2"}, h.Values("X-Trace"))
+		assert.Equal(t, "application/json; charset=utf-8", h.Get("Content-Type"))
+	}
+}
+
+func TestClientInterceptorShortCircuit(t *testing.T) {
+	cached := func(next CallFn) CallFn {
+		return func(ctx context.Context, method string, args []any) (*Response, error) {
+			if method == "cached" {
+				raw := json.RawMessage(`"from cache"`)
+				return &Response{Result: &raw}, nil
+			}
+			return next(ctx, method, args)
+		}
+	}
+	c := &Client{API: "http://127.0.0.1:1", Interceptors: []ClientInterceptor{cached}}
+	v, err := Invoke[string](context.Background(), c, "cached")
+	require.NoError(t, err)
+	assert.Equal(t, "from cache", v)
+}
+
+func TestWithRequestHeader(t *testing.T) {
+	assert.Nil(t, headersFromContext(context.Background()))
+	ctx1 := WithRequestHeader(context.Background(), "k1", "v1")
+	ctx2 := WithRequestHeader(ctx1, "k2", "v2")
+	assert.Equal(t, http.Header{"K1": {"v1"}}, headersFromContext(ctx1), "parent context not changed")
+	assert.Equal(t, http.Header{"K1": {"v1"}, "K2": {"v2"}}, headersFromContext(ctx2))
+}
@@ -1,11 +1,12 @@
 // Package jrpc implements client and server for RPC-like communication over HTTP with json encoded messages.
 // The protocol is somewhat simplified version of json-rpc with a single POST call sending Request json
 // (method name and the list of parameters) and receiving back json Response with "result" json
-// and error string
+// and error string. Full json-rpc 2.0 wire format is supported as well, see WithJSONRPC2 and Client.JSONRPC2.
 package jrpc
 
 import (
 	"encoding/json"
+	"errors"
 )
 
 // Request encloses method name and all params
@@ -15,25 +16,60 @@ type Request struct {
 	ID     uint64 `json:"id"`               // unique call id
 }
 
-// Response encloses result and error received from remote server
+// Response encloses result and error received from remote server.
+// Error code and data set only for structured errors, see Error, and sent next to the error
+// string, so the caller unaware of them still gets the error message.
 type Response struct {
-	Result *json.RawMessage `json:"result,omitempty"` // response json
-	Error  string           `json:"error,omitempty"`  // optional remote (server side / plugin side) error
-	ID     uint64           `json:"id"`               // unique call id, echoed Request.ID to allow calls tracing
+	Result    *json.RawMessage `json:"result,omitempty"`     // response json
+	Error     string           `json:"error,omitempty"`      // optional remote (server side / plugin side) error
+	ErrorCode int              `json:"error_code,omitempty"` // optional code of remote error
+	ErrorData json.RawMessage  `json:"error_data,omitempty"` // optional details of remote error
+	ID        uint64           `json:"id"`                   // unique call id, echoed Request.ID to allow calls tracing
 }
 
-// EncodeResponse convert anything (type interface{}) and incoming error (if any) to Response
+// UnmarshalJSON decodes the response keeping null result as json null, so it can be told apart from the missing one
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type plain Response // without methods, to avoid recursion
+	var v struct {
+		plain
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Response(v.plain)
+	if v.Result != nil {
+		r.Result = &v.Result
+	}
+	return nil
+}
+
+// Err returns remote error as *Error, nil if response has no error
+func (r Response) Err() error {
+	if r.Error == "" {
+		return nil
+	}
+	return &Error{Code: r.ErrorCode, Message: r.Error, Data: r.ErrorData}
+}
+
+// EncodeResponse convert anything (type interface{}) and incoming error (if any) to Response.
+// Code and data of Error, if passed as e or wrapped by it, copied to the response.
 func EncodeResponse(id uint64, resp any, e error) Response {
 	v, err := json.Marshal(&resp)
 	if err != nil {
-		return Response{Error: err.Error()}
+		return Response{Error: err.Error(), ErrorCode: ErrCodeInternal}
 	}
 	if e != nil {
-		return Response{ID: id, Result: nil, Error: e.Error()} // pass input error
+		res := Response{ID: id, Result: nil, Error: e.Error()} // pass input error
+		var rpcErr *Error
+		if errors.As(e, &rpcErr) {
+			res.ErrorCode, res.ErrorData = rpcErr.Code, rpcErr.Data
+		}
+		return res
 	}
 	raw := json.RawMessage{}
 	if err := raw.UnmarshalJSON(v); err != nil {
-		return Response{Error: err.Error()}
+		return Response{Error: err.Error(), ErrorCode: ErrCodeInternal}
 	}
 
 	return Response{ID: id, Result: &raw}
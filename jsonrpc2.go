@@ -0,0 +1,129 @@
+package jrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// jsonrpcVersion is the only version accepted and sent in json-rpc 2.0 mode
+const jsonrpcVersion = "2.0"
+
+// nullID used as response id if request id can't be determined
+var nullID = json.RawMessage("null")
+
+// request2 is json-rpc 2.0 request. Params and ID kept raw, as ID can be a string, number or null.
+type request2 struct {
+	Version string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// clientRequest2 is Request in json-rpc 2.0 envelope, as sent by the client
+type clientRequest2 struct {
+	Version string `json:"jsonrpc"`
+	Request
+}
+
+// response2 is json-rpc 2.0 response, with either result or error set
+type response2 struct {
+	Version string           `json:"jsonrpc"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+	ID      json.RawMessage  `json:"id"`
+}
+
+// validate checks request envelope and returns Error with ErrCodeInvalidRequest if it is not valid
+func (r request2) validate() error {
+	if r.Version != jsonrpcVersion {
+		return NewError(ErrCodeInvalidRequest, fmt.Sprintf("invalid request: unsupported version %q", r.Version))
+	}
+	if r.Method == "" {
+		return NewError(ErrCodeInvalidRequest, "invalid request: no method")
+	}
+	if p := bytes.TrimLeft(r.Params, " \t\r\n"); len(r.Params) > 0 && (len(p) == 0 || p[0] != '[' && p[0] != '{') {
+		return NewError(ErrCodeInvalidRequest, "invalid request: params have to be an array or an object")
+	}
+	if len(r.ID) > 0 {
+		switch r.ID[0] {
+		case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		default:
+			return NewError(ErrCodeInvalidRequest, "invalid request: id has to be a string, number or null")
+		}
+	}
+	return nil
+}
+
+// isNotification checks if request has no id, i.e. it is a notification and has to be left without response.
+// Note: request with null id is not a notification.
+func (r request2) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// numericID returns request id as uint64 to be passed to the handler, zero for non-numeric ids
+func (r request2) numericID() uint64 {
+	id, err := strconv.ParseUint(string(r.ID), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// responseID returns request id to be echoed in the response, null if not set or can't be determined
+func (r request2) responseID() json.RawMessage {
+	if len(r.ID) == 0 {
+		return nullID
+	}
+	return r.ID
+}
+
+// encodeResponse2 converts Response made by the handler to json-rpc 2.0 response with given id.
+// Errors without code reported as ErrCodeInternal, as 2.0 requires code for each error.
+func encodeResponse2(resp Response, id json.RawMessage) response2 {
+	if resp.Error != "" {
+		code := resp.ErrorCode
+		if code == 0 {
+			code = ErrCodeInternal
+		}
+		return response2{Version: jsonrpcVersion, Error: &Error{Code: code, Message: resp.Error, Data: resp.ErrorData}, ID: id}
+	}
+	result := resp.Result
+	if result == nil { // result is required on success, even if null
+		null := json.RawMessage("null")
+		result = &null
+	}
+	return response2{Version: jsonrpcVersion, Result: result, ID: id}
+}
+
+// errorResponse2 makes json-rpc 2.0 response with an error
+func errorResponse2(err *Error, id json.RawMessage) response2 {
+	return response2{Version: jsonrpcVersion, Error: err, ID: id}
+}
+
+// decodeResponse2 reads json-rpc 2.0 response and converts it to Response
+func decodeResponse2(r io.Reader) (Response, error) {
+	var resp2 struct {
+		response2
+		Result json.RawMessage `json:"result"` // keeps null result, unlike pointer
+	}
+	if err := json.NewDecoder(r).Decode(&resp2); err != nil {
+		return Response{}, err
+	}
+	resp := Response{}
+	if resp2.Result != nil {
+		resp.Result = &resp2.Result
+	}
+	if id, err := strconv.ParseUint(string(bytes.Trim(resp2.ID, `"`)), 10, 64); err == nil {
+		resp.ID = id
+	}
+	if resp2.Error != nil {
+		resp.Error, resp.ErrorCode, resp.ErrorData = resp2.Error.Message, resp2.Error.Code, resp2.Error.Data
+		if resp.Error == "" { // message is required by the spec, but not every peer follows it
+			resp.Error = fmt.Sprintf("remote error %d", resp2.Error.Code)
+		}
+	}
+	return resp, nil
+}
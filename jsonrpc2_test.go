@@ -0,0 +1,188 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerJSONRPC2(t *testing.T) {
+	s := NewServer("/v1/cmd", WithJSONRPC2())
+
+	s.Add("sum", func(id uint64, params json.RawMessage) Response {
+		var args []int
+		if err := json.Unmarshal(params, &args); err != nil {
+			return EncodeResponse(id, nil, NewError(ErrCodeInvalidParams, err.Error()))
+		}
+		sum := 0
+		for _, a := range args {
+			sum += a
+		}
+		return EncodeResponse(id, sum, nil)
+	})
+	s.Add("id", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, id, nil)
+	})
+	s.Add("nothing", func(id uint64, params json.RawMessage) Response {
+		return Response{ID: id}
+	})
+	s.Add("fail", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, errors.New("some error"))
+	})
+	s.Add("coded", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, &Error{Code: 42, Message: "coded error", Data: json.RawMessage(`"details"`)})
+	})
+
+	url := startServer(t, s)
+
+	tbl := []struct {
+		name string
+		req  string
+		resp string
+	}{
+		{name: "numeric id", req: `{"jsonrpc":"2.0","method":"sum","params":[1,2,3],"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":6,"id":1}`},
+		{name: "string id", req: `{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":"abc"}`,
+			resp: `{"jsonrpc":"2.0","result":3,"id":"abc"}`},
+		{name: "null id", req: `{"jsonrpc":"2.0","method":"sum","params":[1],"id":null}`,
+			resp: `{"jsonrpc":"2.0","result":1,"id":null}`},
+		{name: "numeric id passed to handler", req: `{"jsonrpc":"2.0","method":"id","id":12345}`,
+			resp: `{"jsonrpc":"2.0","result":12345,"id":12345}`},
+		{name: "string id not passed to handler", req: `{"jsonrpc":"2.0","method":"id","id":"12345"}`,
+			resp: `{"jsonrpc":"2.0","result":0,"id":"12345"}`},
+		{name: "null result", req: `{"jsonrpc":"2.0","method":"nothing","id":1}`,
+			resp: `{"jsonrpc":"2.0","result":null,"id":1}`},
+		{name: "error without code", req: `{"jsonrpc":"2.0","method":"fail","id":2}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"some error"},"id":2}`},
+		{name: "error with code and data", req: `{"jsonrpc":"2.0","method":"coded","id":3}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":42,"message":"coded error","data":"details"},"id":3}`},
+		{name: "invalid params", req: `{"jsonrpc":"2.0","method":"sum","params":{"a":1},"id":4}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32602,"message":"json: cannot unmarshal object into Go value of type []int"},"id":4}`},
+		{name: "parse error", req: `{"jsonrpc":"2.0","method":"sum","params":[1,2`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":null}`},
+		{name: "not an object", req: `"blah"`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: json: cannot unmarshal string into Go value of type jrpc.request2"},"id":null}`},
+		{name: "wrong version", req: `{"jsonrpc":"1.0","method":"sum","id":5}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: unsupported version \"1.0\""},"id":5}`},
+		{name: "no version", req: `{"method":"sum","id":5}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: unsupported version \"\""},"id":5}`},
+		{name: "no method", req: `{"jsonrpc":"2.0","id":6}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: no method"},"id":6}`},
+		{name: "bad id", req: `{"jsonrpc":"2.0","method":"sum","id":{"a":1}}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: id has to be a string, number or null"},"id":{"a":1}}`},
+		{name: "scalar params", req: `{"jsonrpc":"2.0","method":"sum","params":1,"id":8}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: params have to be an array or an object"},"id":8}`},
+		{name: "null params", req: `{"jsonrpc":"2.0","method":"sum","params":null,"id":9}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: params have to be an array or an object"},"id":9}`},
+		{name: "unknown method", req: `{"jsonrpc":"2.0","method":"blah","id":7}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32601,"message":"unsupported method"},"id":7}`},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(url+"/v1/cmd", "application/json", strings.NewReader(tt.req))
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resp+"\n", string(data))
+		})
+	}
+}
+
+func TestClientJSONRPC2(t *testing.T) {
+	s := NewServer("/v1/cmd", WithJSONRPC2())
+	Handle(s, "greet", func(_ context.Context, name string) (string, error) {
+		if name == "" {
+			return "", &Error{Code: 400, Message: "no name", Data: json.RawMessage(`{"field":"name"}`)}
+		}
+		return "hello " + name, nil
+	})
+	url := startServer(t, s)
+
+	c := &Client{API: url + "/v1/cmd", JSONRPC2: true}
+
+	t.Run("result", func(t *testing.T) {
+		r, err := c.Call("greet", "user")
+		require.NoError(t, err)
+		assert.JSONEq(t, `"hello user"`, string(*r.Result))
+		assert.Equal(t, uint64(1), r.ID)
+
+		res, err := Invoke[string](context.Background(), c, "greet", "user")
+		require.NoError(t, err)
+		assert.Equal(t, "hello user", res)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := c.Call("greet", "")
+		require.EqualError(t, err, "no name")
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, &Error{Code: 400, Message: "no name", Data: json.RawMessage(`{"field":"name"}`)}, rpcErr)
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		_, err := c.Call("blah")
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, ErrCodeMethodNotFound, rpcErr.Code)
+	})
+
+	t.Run("legacy client", func(t *testing.T) {
+		_, err := (&Client{API: url + "/v1/cmd"}).Call("greet", "user")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode response for greet")
+	})
+}
+
+func TestClientJSONRPC2Wire(t *testing.T) {
+	tbl := []struct {
+		name   string
+		args   []any
+		req    string
+		resp   string
+		result string
+		err    *Error
+	}{
+		{name: "string id in response", args: []any{1, 2}, req: `{"jsonrpc":"2.0","method":"test","params":[1,2],"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":"1"}`, result: `"ok"`},
+		{name: "single string wrapped", args: []any{"abc"}, req: `{"jsonrpc":"2.0","method":"test","params":["abc"],"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":1}`, result: `"ok"`},
+		{name: "single number wrapped", args: []any{42}, req: `{"jsonrpc":"2.0","method":"test","params":[42],"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":1}`, result: `"ok"`},
+		{name: "single nil wrapped", args: []any{nil}, req: `{"jsonrpc":"2.0","method":"test","params":[null],"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":1}`, result: `"ok"`},
+		{name: "single object as is", args: []any{struct{ A int }{1}}, req: `{"jsonrpc":"2.0","method":"test","params":{"A":1},"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":1}`, result: `"ok"`},
+		{name: "single list as is", args: []any{[]int{1, 2}}, req: `{"jsonrpc":"2.0","method":"test","params":[1,2],"id":1}`,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":1}`, result: `"ok"`},
+		{name: "no params", req: `{"jsonrpc":"2.0","method":"test","id":1}`,
+			resp: `{"jsonrpc":"2.0","result":{"a":1},"id":1}`, result: `{"a":1}`},
+		{name: "error without message", req: `{"jsonrpc":"2.0","method":"test","id":1}`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32000},"id":1}`, err: &Error{Code: -32000, Message: "remote error -32000"}},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := testServer(t, tt.req, tt.resp)
+			defer ts.Close()
+			c := Client{API: ts.URL, JSONRPC2: true}
+			r, err := c.Call("test", tt.args...)
+			if tt.err != nil {
+				assert.Equal(t, tt.err, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.result, string(*r.Result))
+			assert.Equal(t, uint64(1), r.ID)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package jrpc
+
+import (
+	"context"
+	"sync"
+)
+
+// notifyQueue is a bounded queue of notifications processed in the background by a fixed number of workers
+type notifyQueue struct {
+	ch chan func()
+	wg sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newNotifyQueue makes queue with given size and starts workers
+func newNotifyQueue(size, workers int) *notifyQueue {
+	q := &notifyQueue{ch: make(chan func(), size)}
+	for range max(workers, 1) {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for fn := range q.ch {
+				fn()
+			}
+		}()
+	}
+	return q
+}
+
+// push adds fn to the queue, returns false if the queue is full or closed
+func (q *notifyQueue) push(fn func()) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.ch <- fn:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops accepting new notifications and waits for the queued ones to be processed, or ctx done
+func (q *notifyQueue) close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+package jrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerNotify(t *testing.T) {
+	for _, jsonrpc2 := range []bool{false, true} {
+		t.Run(map[bool]string{false: "default", true: "jsonrpc2"}[jsonrpc2], func(t *testing.T) {
+			var opts []Option
+			if jsonrpc2 {
+				opts = append(opts, WithJSONRPC2())
+			}
+			s := NewServer("/v1/cmd", opts...)
+
+			got := make(chan string, 10)
+			Handle(s, "ping", func(_ context.Context, v string) (string, error) {
+				got <- v
+				return "pong", nil
+			})
+			url := startServer(t, s)
+
+			c := Client{API: url + "/v1/cmd", JSONRPC2: jsonrpc2}
+			require.NoError(t, c.Notify("ping", "abc"))
+			select {
+			case v := <-got:
+				assert.Equal(t, "abc", v)
+			default:
+				t.Fatal("handler has to finish before Notify returned")
+			}
+		})
+	}
+}
+
+func TestServerNotifyWire(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "ok", nil) })
+	url := startServer(t, s)
+
+	s2 := NewServer("/v1/cmd", WithJSONRPC2())
+	s2.Add("fn", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "ok", nil) })
+	url2 := startServer(t, s2)
+
+	tbl := []struct {
+		name   string
+		url    string
+		req    string
+		status int
+		resp   string
+	}{
+		{name: "no id is a notification, not id 0", url: url, req: `{"method":"fn","params":[1]}`, status: http.StatusNoContent},
+		{name: "id 0 answered", url: url, req: `{"method":"fn","id":0}`, status: http.StatusOK,
+			resp: `{"result":"ok","id":0}` + "\n"},
+		{name: "unknown method", url: url, req: `{"method":"blah"}`, status: http.StatusNotImplemented,
+			resp: `{"error":"unsupported method","error_code":-32601,"id":0}` + "\n"},
+		{name: "batch of notifications", url: url, req: `[{"method":"fn"},{"method":"blah"}]`, status: http.StatusNoContent},
+		{name: "batch with notification", url: url, req: `[{"method":"fn"},{"method":"fn","id":5}]`, status: http.StatusOK,
+			resp: `[{"result":"ok","id":5}]` + "\n"},
+		{name: "2.0 notification", url: url2, req: `{"jsonrpc":"2.0","method":"fn"}`, status: http.StatusNoContent},
+		{name: "2.0 unknown method", url: url2, req: `{"jsonrpc":"2.0","method":"blah"}`, status: http.StatusNoContent},
+		{name: "2.0 null id is not a notification", url: url2, req: `{"jsonrpc":"2.0","method":"fn","id":null}`, status: http.StatusOK,
+			resp: `{"jsonrpc":"2.0","result":"ok","id":null}` + "\n"},
+		{name: "2.0 batch of notifications", url: url2, req: `[{"jsonrpc":"2.0","method":"fn"}]`, status: http.StatusNoContent},
+		{name: "2.0 batch with notification", url: url2, req: `[{"jsonrpc":"2.0","method":"fn"},{"jsonrpc":"2.0","method":"fn","id":"x"}]`,
+			status: http.StatusOK, resp: `[{"jsonrpc":"2.0","result":"ok","id":"x"}]` + "\n"},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(tt.url+"/v1/cmd", "application/json", bytes.NewBufferString(tt.req))
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resp, string(data))
+		})
+	}
+}
+
+func TestServerNotifyQueue(t *testing.T) {
+	s := NewServer("/v1/cmd", WithNotifyQueue(2, 1))
+
+	unblock := make(chan struct{})
+	var mu sync.Mutex
+	var got []string
+	Handle(s, "slow", func(ctx context.Context, v string) (any, error) {
+		<-unblock
+		mu.Lock()
+		got = append(got, v)
+		mu.Unlock()
+		assert.NoError(t, ctx.Err(), "context of queued notification not canceled with the request")
+		return nil, nil
+	})
+
+	l := listen(t)
+	s.activate()
+	done := make(chan error, 1)
+	go func() { done <- s.serve(l) }()
+
+	c := Client{API: "http://" + l.Addr().String() + "/v1/cmd"}
+
+	// one notification taken by the worker and two queued, all answered right away
+	st := time.Now()
+	for _, v := range []string{"n1", "n2", "n3"} {
+		require.NoError(t, c.Notify("slow", v))
+	}
+	assert.Less(t, time.Since(st), 500*time.Millisecond)
+
+	// the queue is full, the next one processed synchronously
+	syncDone := make(chan error)
+	go func() { syncDone <- c.Notify("slow", "n4") }()
+	select {
+	case <-syncDone:
+		t.Fatal("notification with full queue should wait for the handler")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(unblock)
+	require.NoError(t, <-syncDone)
+
+	// shutdown waits for queued notifications
+	require.NoError(t, s.Shutdown())
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"n1", "n2", "n3", "n4"}, got)
+	mu.Unlock()
+}
+
+func TestClient_Notify(t *testing.T) {
+	ts := testServer(t, `{"method":"test","params":[123,"abc"]}`, ``)
+	defer ts.Close()
+	c := Client{API: ts.URL}
+	assert.NoError(t, c.Notify("test", 123, "abc"))
+
+	ts2 := testServer(t, `{"jsonrpc":"2.0","method":"test"}`, `ignored body`)
+	defer ts2.Close()
+	c = Client{API: ts2.URL, JSONRPC2: true}
+	assert.NoError(t, c.NotifyContext(context.Background(), "test"))
+
+	c = Client{API: "http://127.0.0.2", Client: http.Client{Timeout: 10 * time.Millisecond}}
+	err := c.Notify("test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remote call failed for test")
+}
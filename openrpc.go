@@ -0,0 +1,108 @@
+package jrpc
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/go-pkgz/rest"
+)
+
+// openrpcVersion is the version of OpenRPC specification the document generated for
+const openrpcVersion = "1.3.2"
+
+// OpenRPCDocument is OpenRPC (https://spec.open-rpc.org) description of the server, see Server.OpenRPC
+type OpenRPCDocument struct {
+	OpenRPC string          `json:"openrpc"`
+	Info    OpenRPCInfo     `json:"info"`
+	Methods []OpenRPCMethod `json:"methods"`
+}
+
+// OpenRPCInfo is the title and version of the api
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a single method. Params of typed handlers with struct params are listed by name,
+// one for each field. Any other typed params described by position as a single "params" value, i.e. ["abc"],
+// the way json-rpc 2.0 client sends a single arg. Params of untyped handlers are unknown and left unspecified.
+type OpenRPCMethod struct {
+	Name           string           `json:"name"`
+	Tags           []OpenRPCTag     `json:"tags,omitempty"`
+	ParamStructure string           `json:"paramStructure,omitempty"`
+	Params         []OpenRPCContent `json:"params,omitempty"`
+	Result         *OpenRPCContent  `json:"result,omitempty"`
+}
+
+// OpenRPCTag is a tag of the method, the prefix of the group for methods added with Group
+type OpenRPCTag struct {
+	Name string `json:"name"`
+}
+
+// OpenRPCContent describes a single param or result with its json schema
+type OpenRPCContent struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+// OpenRPC returns OpenRPC document describing all the registered methods. Title and version of the document
+// taken from WithSignature, if set. Schemas of params and results reflected for typed handlers, see Handle,
+// and left empty, i.e. any value, for the rest. Methods added with Group tagged with the group prefix.
+func (s *Server) OpenRPC() OpenRPCDocument {
+	s.httpServer.Lock()
+	defer s.httpServer.Unlock()
+	return s.openRPC()
+}
+
+// openRPC is OpenRPC without lock, safe to call only for activated server
+func (s *Server) openRPC() OpenRPCDocument {
+	doc := OpenRPCDocument{
+		OpenRPC: openrpcVersion,
+		Info:    OpenRPCInfo{Title: s.signature.appName, Version: s.signature.version},
+		Methods: make([]OpenRPCMethod, 0, len(s.funcs.m)),
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "jrpc"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "0.0.0"
+	}
+
+	for name, h := range s.funcs.m {
+		m := OpenRPCMethod{Name: name, Result: &OpenRPCContent{Name: "result", Schema: &Schema{}}}
+		if h.group != "" {
+			m.Tags = []OpenRPCTag{{Name: h.group}}
+		}
+		if h.result != nil {
+			m.Result.Schema = schemaOf(h.result)
+		}
+		if h.params != nil {
+			m.ParamStructure, m.Params = openRPCParams(h.params)
+		}
+		doc.Methods = append(doc.Methods, m)
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	return doc
+}
+
+// openRPCParams describes params of type t. Struct params described by name, as jrpc sends struct as an object
+// with fields as keys. Anything else described by position as a single value wrapped in array, i.e. ["abc"] or
+// [[1,2]], accepted by typed handlers along with the unwrapped value sent by the client in the default mode.
+func openRPCParams(t reflect.Type) (structure string, params []OpenRPCContent) {
+	schema := schemaOf(t)
+	if schema.Type != "object" || schema.Properties == nil {
+		return "by-position", []OpenRPCContent{{Name: "params", Schema: schema}}
+	}
+	params = make([]OpenRPCContent, 0, len(schema.Properties))
+	for name, fieldSchema := range schema.Properties {
+		params = append(params, OpenRPCContent{Name: name, Schema: fieldSchema})
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return "by-name", params
+}
+
+// openRPCHndl serves OpenRPC document on GET request to the api url
+func (s *Server) openRPCHndl(w http.ResponseWriter, _ *http.Request) {
+	rest.RenderJSON(w, s.openRPC())
+}
@@ -0,0 +1,125 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerOpenRPC(t *testing.T) {
+	type saveReq struct {
+		Key   string `json:"key"`
+		Value int    `json:"value"`
+	}
+
+	s := NewServer("/v1/cmd", WithSignature("store-plugin", "umputun", "1.2.3"))
+	Handle(s, "store.save", func(_ context.Context, p saveReq) (bool, error) { return true, nil })
+	Handle(s, "store.count", func(_ context.Context, p string) (int, error) { return 1, nil })
+	s.Group("store", HandlersGroup{
+		"load": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "value", nil) },
+	})
+
+	b, err := json.Marshal(s.OpenRPC())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"openrpc": "1.3.2",
+		"info": {"title": "store-plugin", "version": "1.2.3"},
+		"methods": [
+			{"name": "store.count", "paramStructure": "by-position",
+				"params": [{"name": "params", "schema": {"type": "string"}}],
+				"result": {"name": "result", "schema": {"type": "integer"}}},
+			{"name": "store.load", "tags": [{"name": "store"}],
+				"result": {"name": "result", "schema": {}}},
+			{"name": "store.save", "paramStructure": "by-name",
+				"params": [{"name": "key", "schema": {"type": "string"}}, {"name": "value", "schema": {"type": "integer"}}],
+				"result": {"name": "result", "schema": {"type": "boolean"}}}
+		]
+	}`, string(b))
+}
+
+func TestServerOpenRPCParamsOnWire(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	Handle(s, "store.count", func(_ context.Context, p string) (int, error) { return len(p), nil })
+	Handle(s, "store.sum", func(_ context.Context, p []int) (int, error) { return len(p), nil })
+	url := startServer(t, s)
+
+	doc := s.OpenRPC()
+	for _, m := range doc.Methods {
+		assert.Equal(t, "by-position", m.ParamStructure, m.Name)
+		assert.Len(t, m.Params, 1, m.Name)
+	}
+
+	// params sent by position as described in the document
+	tbl := []struct{ req, resp string }{
+		{req: `{"method":"store.count","params":["abc"],"id":1}`, resp: `{"result":3,"id":1}`},
+		{req: `{"method":"store.sum","params":[[1,2]],"id":2}`, resp: `{"result":2,"id":2}`},
+		{req: `{"method":"store.count","params":"abc","id":3}`, resp: `{"result":3,"id":3}`},
+		{req: `{"method":"store.sum","params":[1,2],"id":4}`, resp: `{"result":2,"id":4}`},
+	}
+	for _, tt := range tbl {
+		resp, err := http.Post(url+"/v1/cmd", "application/json", strings.NewReader(tt.req))
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.JSONEq(t, tt.resp, string(body), tt.req)
+	}
+}
+
+func TestServerOpenRPCDefaultInfo(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	doc := s.OpenRPC()
+	assert.Equal(t, OpenRPCInfo{Title: "jrpc", Version: "0.0.0"}, doc.Info)
+	assert.Empty(t, doc.Methods)
+}
+
+func TestServerOpenRPCEndpoint(t *testing.T) {
+	s := NewServer("/v1/cmd", Auth("user", "passwd"), WithOpenRPC())
+	s.Group("store", HandlersGroup{
+		"load": func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "value", nil) },
+	})
+	url := startServer(t, s)
+
+	req, err := http.NewRequest("GET", url+"/v1/cmd", http.NoBody)
+	require.NoError(t, err)
+	req.SetBasicAuth("user", "passwd")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var doc OpenRPCDocument
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	require.Len(t, doc.Methods, 1)
+	assert.Equal(t, "store.load", doc.Methods[0].Name)
+
+	// calls still served on the same url
+	r, err := (&Client{API: url + "/v1/cmd", AuthUser: "user", AuthPasswd: "passwd"}).Call("store.load")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"value"`, string(*r.Result))
+
+	// guarded by the same auth
+	resp2, err := http.Get(url + "/v1/cmd")
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp2.Body)
+	_ = resp2.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp2.StatusCode)
+}
+
+func TestServerOpenRPCDisabled(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return Response{} })
+	url := startServer(t, s)
+
+	resp, err := http.Get(url + "/v1/cmd")
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
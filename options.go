@@ -1,7 +1,9 @@
 package jrpc
 
 import (
+	"crypto/tls"
 	"net/http"
+	"time"
 )
 
 // Option func type
@@ -17,6 +19,42 @@ func Auth(user, password string) Option {
 	}
 }
 
+// WithAuthenticator sets authentication of requests, optional. Request without valid credentials rejected with 401,
+// and the principal of authenticated one put to the request context, see PrincipalFromContext.
+// See BasicAuth, BearerTokens and AuthChain for the common cases. Takes precedence over Auth.
+func WithAuthenticator(a Authenticator) Option {
+	return func(s *Server) {
+		s.authenticator = a
+	}
+}
+
+// WithHMAC enables checking of HMAC signatures of requests, made by Client with HMACKey, optional.
+// Keys are shared secrets by key id. Requests with bad signature, timestamp off by more than window or nonce
+// already seen within the window rejected with 401. The window is 5 minutes if not set. The key id put to the
+// request context as the principal, unless already set by the authenticator, see WithAuthenticator.
+func WithHMAC(keys map[string][]byte, window time.Duration) Option {
+	return func(s *Server) {
+		s.hmac = newHMACVerifier(keys, window)
+	}
+}
+
+// WithTLS enables TLS with certificate and key from pem files, optional. Can be combined with WithTLSConfig,
+// i.e. to verify client certificates, the certificate added to the certificates of the config.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.tls.certFile, s.tls.keyFile = certFile, keyFile
+	}
+}
+
+// WithTLSConfig enables TLS with the config, optional. For mutual TLS the config has to verify client certificates,
+// i.e. with ClientAuth set to tls.RequireAndVerifyClientCert and ClientCAs made by LoadCertPool. Verified client
+// certificate is available to handlers, see PeerCertificateFromContext, and can authenticate calls, see ClientCertAuth.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) {
+		s.tls.config = cfg
+	}
+}
+
 // WithTimeouts sets server timeout values such as ReadHeader, Write and Idle timeout, optional.
 // If this option not defined server use default timeout values
 func WithTimeouts(timeouts Timeouts) Option {
@@ -46,6 +84,25 @@ func WithThrottler(limit int) Option {
 	}
 }
 
+// WithBatchConcurrency sets max number of calls of a single batch running concurrently, optional.
+// By default calls of the batch run one by one, in the order of the batch.
+func WithBatchConcurrency(limit int) Option {
+	return func(s *Server) {
+		s.limits.batchConcurrency = limit
+	}
+}
+
+// WithNotifyQueue enables background processing of notifications, optional. Notifications are put to
+// the queue of given size, answered right away and processed by the given number of workers.
+// If the queue is full notification processed synchronously, as without the queue.
+// By default notification handler runs synchronously, and the response sent after it finished.
+func WithNotifyQueue(size, workers int) Option {
+	return func(s *Server) {
+		s.notifications.size = size
+		s.notifications.workers = workers
+	}
+}
+
 // WithMiddlewares sets custom middlewares list, optional
 func WithMiddlewares(middlewares ...func(http.Handler) http.Handler) Option {
 	return func(s *Server) {
@@ -70,3 +127,59 @@ func WithLogger(logger L) Option {
 		s.logger = logger
 	}
 }
+
+// WithJSONRPC2 switches the server to json-rpc 2.0 protocol, optional. Requests have to be sent
+// with "jsonrpc":"2.0", ids can be strings, numbers or null, and errors are sent as error objects
+// with code, message and data. Broken requests and unknown methods reported with 200 status and
+// error object, not with http error status. Handlers get zero id for non-numeric request ids.
+// Clients have to set Client.JSONRPC2 to talk to such server.
+func WithJSONRPC2() Option {
+	return func(s *Server) {
+		s.jsonrpc2 = true
+	}
+}
+
+// WithDiscovery enables built-in "rpc.methods" method returning the list of registered methods, optional.
+// For typed handlers json schemas of params and result included, see Server.Methods. The method served
+// the same way as any other one, with the same auth and middlewares.
+func WithDiscovery() Option {
+	return func(s *Server) {
+		s.discovery = true
+	}
+}
+
+// WithOpenRPC enables serving of OpenRPC document on GET request to the api url, optional.
+// The document is the same as returned by Server.OpenRPC and guarded by the same auth and middlewares as calls.
+func WithOpenRPC() Option {
+	return func(s *Server) {
+		s.openrpc = true
+	}
+}
+
+// WithHealthChecks enables GET /health and /ready endpoints, optional. Both run the checks added with
+// Server.AddCheck and respond with json status of each, 503 if any failed. /ready fails as well while the
+// server drains on shutdown. Served next to /ping, before auth and logging, so probes need no credentials.
+func WithHealthChecks() Option {
+	return func(s *Server) {
+		s.health.enabled = true
+	}
+}
+
+// WithInterceptors sets rpc level interceptors applied to all the methods, optional.
+// See Interceptor and Server.Intercept for interceptors of specific methods and groups.
+func WithInterceptors(ics ...Interceptor) Option {
+	return func(s *Server) {
+		for _, ic := range ics {
+			s.interceptors = append(s.interceptors, scopedInterceptor{ic: ic})
+		}
+	}
+}
+
+// WithAuthorizer sets authorization of calls, optional. Each call checked by the authorizer with the method
+// name and the principal of the caller in the context, see PrincipalFromContext. Denied call answered with
+// Error with ErrCodeForbidden code, or the Error returned by the authorizer. See AllowList for the simple case.
+func WithAuthorizer(a Authorizer) Option {
+	return func(s *Server) {
+		s.authorizer = a
+	}
+}
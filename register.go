@@ -0,0 +1,119 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode"
+)
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+// Register adds all exported methods of svc with supported signature as a group with prefix, in the spirit
+// of net/rpc. Each method served as prefix.method, with the first letter (or the leading acronym) of go method
+// name lowered, i.e. Save served as "store.save" and GetByID as "store.getByID".
+//
+// Supported methods take optional context.Context and at most one param after it, and return either error
+// or a single value and error, i.e. func(ctx context.Context, p P) (R, error) or func() error. Params decoded
+// and the result encoded the same way as for Handle. Methods with any other signature are not registered and
+// reported by the returned error, the rest of methods registered anyway.
+func (s *Server) Register(prefix string, svc any) error {
+	if svc == nil {
+		return fmt.Errorf("can't register nil service for %s", prefix)
+	}
+
+	v := reflect.ValueOf(svc)
+	t := v.Type()
+	if t.NumMethod() == 0 {
+		return fmt.Errorf("no exported methods in %s for %s", t, prefix)
+	}
+
+	var errs []error
+	for i := range t.NumMethod() {
+		name := t.Method(i).Name
+		h, err := reflectHandler(v.Method(i))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("method %s.%s: %w", t, name, err))
+			continue
+		}
+		h.group = prefix
+		s.add(prefix+"."+MethodName(name), h)
+	}
+	return errors.Join(errs...)
+}
+
+// reflectHandler makes handler calling fn, error returned if fn signature not supported
+func reflectHandler(fn reflect.Value) (handlerInfo, error) {
+	ft := fn.Type()
+	if ft.IsVariadic() {
+		return handlerInfo{}, errors.New("variadic params not supported")
+	}
+
+	in := make([]reflect.Type, 0, ft.NumIn())
+	for i := range ft.NumIn() {
+		in = append(in, ft.In(i))
+	}
+	withCtx := len(in) > 0 && in[0] == contextType
+	if withCtx {
+		in = in[1:]
+	}
+	if len(in) > 1 {
+		return handlerInfo{}, fmt.Errorf("expected at most one param besides context, got %d", len(in))
+	}
+
+	if ft.NumOut() == 0 || ft.NumOut() > 2 || ft.Out(ft.NumOut()-1) != errorType {
+		return handlerInfo{}, errors.New("has to return error or a value and error")
+	}
+
+	h := handlerInfo{}
+	if len(in) == 1 {
+		h.params = in[0]
+	}
+	if ft.NumOut() == 2 {
+		h.result = ft.Out(0)
+	}
+
+	h.fn = func(ctx context.Context, id uint64, params json.RawMessage) Response {
+		args := make([]reflect.Value, 0, 2)
+		if withCtx {
+			args = append(args, reflect.ValueOf(&ctx).Elem())
+		}
+		if h.params != nil {
+			p := reflect.New(h.params)
+			if err := DecodeParams(params, p.Interface()); err != nil {
+				return EncodeResponse(id, nil, err)
+			}
+			args = append(args, p.Elem())
+		}
+
+		out := fn.Call(args)
+		err, _ := out[len(out)-1].Interface().(error)
+		if len(out) == 1 {
+			return EncodeResponse(id, nil, err)
+		}
+		return EncodeResponse(id, out[0].Interface(), err)
+	}
+	return h, nil
+}
+
+// MethodName makes method name from go name the way Register does, lowering the first letter or the leading acronym,
+// i.e. Save -> save, GetByID -> getByID, URLFor -> urlFor, ID -> id. Used by jrpcgen to name generated methods the same way.
+func MethodName(name string) string {
+	runes := []rune(name)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		upper-- // keep the first letter of the next word, i.e. F in URLFor
+	}
+	for i := range max(upper, 1) {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,157 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type testStore struct {
+	sync.Mutex // promoted Lock and Unlock have unsupported signature
+	data       map[string]string
+}
+
+func (s *testStore) Save(_ context.Context, rec testRecord) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	s.data[rec.Key] = rec.Value
+	return true, nil
+}
+
+func (s *testStore) Load(key string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.data[key]
+	if !ok {
+		return "", NewError(404, "not found "+key)
+	}
+	return v, nil
+}
+
+func (s *testStore) CountAll(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	return len(s.data), nil
+}
+
+func (s *testStore) Reset() error {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.data) == 0 {
+		return errors.New("nothing to reset")
+	}
+	s.data = map[string]string{}
+	return nil
+}
+
+func (s *testStore) Find(prefix string, limit int) ([]string, error) { return nil, nil }
+
+func (s *testStore) Keys(...string) ([]string, error) { return nil, nil }
+
+func (s *testStore) Size() int { return 0 }
+
+func TestServerRegister(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	err := s.Register("store", &testStore{data: map[string]string{}})
+	require.Error(t, err)
+	for _, m := range []string{
+		"method *jrpc.testStore.Find: expected at most one param besides context, got 2",
+		"method *jrpc.testStore.Keys: variadic params not supported",
+		"method *jrpc.testStore.Lock: has to return error or a value and error",
+		"method *jrpc.testStore.Size: has to return error or a value and error",
+		"method *jrpc.testStore.Unlock: has to return error or a value and error",
+	} {
+		assert.Contains(t, err.Error(), m)
+	}
+
+	methods := s.Methods()
+	require.Len(t, methods, 4)
+	assert.Equal(t, "store.countAll", methods[0].Name)
+	assert.Equal(t, "store.load", methods[1].Name)
+	assert.Equal(t, &Schema{Type: "string"}, methods[1].Params)
+	assert.Equal(t, "store.reset", methods[2].Name)
+	assert.Nil(t, methods[2].Params)
+	assert.Nil(t, methods[2].Result)
+	assert.Equal(t, "store.save", methods[3].Name)
+	assert.Equal(t, &Schema{Type: "boolean"}, methods[3].Result)
+
+	url := startServer(t, s)
+	c := &Client{API: url + "/v1/cmd"}
+	ctx := context.Background()
+
+	ok, err := Invoke[bool](ctx, c, "store.save", testRecord{Key: "k1", Value: "v1"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	v, err := Invoke[string](ctx, c, "store.load", "k1")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	_, err = c.Call("store.load", "k2")
+	var rpcErr *Error
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, 404, rpcErr.Code)
+	assert.Equal(t, "not found k2", rpcErr.Message)
+
+	_, err = c.Call("store.load", 123)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, ErrCodeInvalidParams, rpcErr.Code)
+
+	count, err := Invoke[int](ctx, c, "store.countAll")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	resp, err := c.Call("store.reset")
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(*resp.Result), "null result kept")
+	_, err = c.Call("store.reset")
+	assert.EqualError(t, err, "nothing to reset")
+}
+
+func TestServerRegisterFunc(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	err := s.Register("greet", greeter("hello"))
+	require.NoError(t, err)
+	url := startServer(t, s)
+
+	v, err := Invoke[string](context.Background(), &Client{API: url + "/v1/cmd"}, "greet.greet", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "hello bob", v)
+}
+
+type greeter string
+
+func (g greeter) Greet(name string) (string, error) { return fmt.Sprintf("%s %s", g, name), nil }
+
+func TestServerRegisterFailed(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	assert.EqualError(t, s.Register("store", nil), "can't register nil service for store")
+	assert.EqualError(t, s.Register("store", testRecord{}), "no exported methods in jrpc.testRecord for store")
+	assert.Empty(t, s.Methods())
+}
+
+func TestMethodName(t *testing.T) {
+	tbl := []struct{ in, out string }{
+		{"Save", "save"},
+		{"GetByID", "getByID"},
+		{"URLFor", "urlFor"},
+		{"ID", "id"},
+		{"X", "x"},
+	}
+	for _, tt := range tbl {
+		assert.Equal(t, tt.out, MethodName(tt.in), tt.in)
+	}
+}
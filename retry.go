@@ -0,0 +1,83 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// defaultRetryStatuses are http statuses retried if RetryPolicy.Statuses not set
+var defaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable}
+
+// RetryPolicy defines retries of failed calls, see Client.Retry. Only calls of Idempotent methods are retried,
+// and only on client-level failures, like refused connection or http statuses from Statuses list.
+// Remote errors returned by the handler are never retried.
+type RetryPolicy struct {
+	MaxAttempts int                  // max number of attempts, including the first one, no retries if less than 2
+	BaseDelay   time.Duration        // delay before the first retry, doubled for each next one, 100ms if not set
+	MaxDelay    time.Duration        // max delay between attempts, optional
+	Statuses    []int                // http statuses to retry, 429, 502 and 503 if not set
+	Idempotent  []string             // methods safe to be called more than once, only them retried
+	Retryable   func(err error) bool // custom check of retryable errors, replaces refused connection and Statuses checks
+}
+
+// isIdempotent checks if method is marked as idempotent
+func (p *RetryPolicy) isIdempotent(method string) bool {
+	return slices.Contains(p.Idempotent, method)
+}
+
+// isRetryable checks if error of the attempt worth another try
+func (p *RetryPolicy) isRetryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = defaultRetryStatuses
+	}
+	return slices.Contains(statuses, statusErr.StatusCode)
+}
+
+// delay returns jittered delay before the next attempt, attempt counted from 1
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	if d <= 0 {
+		d = 100 * time.Millisecond
+	}
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// equal jitter, half of the delay is fixed and another half is random
+	return d/2 + time.Duration(rand.Int64N(int64(d/2)+1)) //nolint:gosec // no need for crypto rand in jitter
+}
+
+// wait sleeps before the next attempt. Returns false without waiting if ctx deadline comes before
+// the end of the delay, and as soon as ctx done.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) bool {
+	d := p.delay(attempt)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
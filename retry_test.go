@@ -0,0 +1,179 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Retry(t *testing.T) {
+	var attempts atomic.Int32
+	failures := int32(2)
+	status := http.StatusServiceUnavailable
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"result":"ok","id":1}`)
+	}))
+	defer ts.Close()
+
+	reset := func(f int32, st int) {
+		attempts.Store(0)
+		failures, status = f, st
+	}
+
+	c := Client{API: ts.URL, Retry: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Idempotent: []string{"get", "get2"}}}
+
+	t.Run("idempotent retried", func(t *testing.T) {
+		reset(2, http.StatusServiceUnavailable)
+		r, err := c.Call("get")
+		require.NoError(t, err)
+		assert.JSONEq(t, `"ok"`, string(*r.Result))
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		reset(3, http.StatusTooManyRequests)
+		_, err := c.Call("get")
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.EqualError(t, err, "bad status 429 Too Many Requests for get")
+		assert.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("not idempotent", func(t *testing.T) {
+		reset(1, http.StatusServiceUnavailable)
+		_, err := c.Call("save")
+		assert.EqualError(t, err, "bad status 503 Service Unavailable for save")
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("not retryable status", func(t *testing.T) {
+		reset(1, http.StatusInternalServerError)
+		_, err := c.Call("get")
+		assert.EqualError(t, err, "bad status 500 Internal Server Error for get")
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("custom statuses", func(t *testing.T) {
+		reset(1, http.StatusInternalServerError)
+		cc := Client{API: ts.URL, Retry: &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond,
+			Idempotent: []string{"get"}, Statuses: []int{http.StatusInternalServerError}}}
+		_, err := cc.Call("get")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+
+	t.Run("batch of idempotent methods", func(t *testing.T) {
+		reset(1, http.StatusBadGateway)
+		_, err := c.CallBatch(context.Background(), BatchCall{Method: "get"}, BatchCall{Method: "get2"})
+		require.Error(t, err) // retried, but the response is not a batch
+		assert.Contains(t, err.Error(), "failed to decode response for batch")
+		assert.Equal(t, int32(2), attempts.Load())
+
+		reset(1, http.StatusBadGateway)
+		_, err = c.CallBatch(context.Background(), BatchCall{Method: "get"}, BatchCall{Method: "save"})
+		assert.EqualError(t, err, "bad status 502 Bad Gateway for batch")
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		reset(1, http.StatusServiceUnavailable)
+		require.NoError(t, c.Notify("get"))
+		assert.Equal(t, int32(2), attempts.Load())
+	})
+}
+
+func TestClient_RetryConnRefused(t *testing.T) {
+	l := listen(t)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	var errs []error
+	c := Client{API: "http://" + addr, Retry: &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Idempotent: []string{"get"},
+		Retryable: func(err error) bool {
+			errs = append(errs, err)
+			return (&RetryPolicy{}).isRetryable(err)
+		}}}
+	_, err := c.Call("get")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+	assert.Len(t, errs, 2, "retryable checked for each failed attempt but the last one")
+}
+
+func TestClient_RetryContext(t *testing.T) {
+	var attempts atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	c := Client{API: ts.URL, Retry: &RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, Idempotent: []string{"get"}}}
+
+	t.Run("deadline before the next attempt", func(t *testing.T) {
+		attempts.Store(0)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		st := time.Now()
+		_, err := c.CallContext(ctx, "get")
+		assert.EqualError(t, err, "bad status 503 Service Unavailable for get")
+		assert.Less(t, time.Since(st), 150*time.Millisecond, "shouldn't wait for the attempt it has no time for")
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		attempts.Store(0)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		st := time.Now()
+		_, err := c.CallContext(ctx, "get")
+		require.Error(t, err)
+		assert.Less(t, time.Since(st), 400*time.Millisecond)
+		assert.Equal(t, int32(1), attempts.Load())
+	})
+}
+
+func TestRetryPolicy_delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for _, tt := range []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 3, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+		{attempt: 10, min: 150 * time.Millisecond, max: 300 * time.Millisecond},
+	} {
+		for range 20 {
+			d := p.delay(tt.attempt)
+			assert.GreaterOrEqual(t, d, tt.min, "attempt %d", tt.attempt)
+			assert.LessOrEqual(t, d, tt.max, "attempt %d", tt.attempt)
+		}
+	}
+
+	d := (&RetryPolicy{}).delay(1)
+	assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+	assert.LessOrEqual(t, d, 100*time.Millisecond)
+}
+
+func TestRetryPolicy_isRetryable(t *testing.T) {
+	p := RetryPolicy{}
+	assert.True(t, p.isRetryable(fmt.Errorf("wrapped: %w", syscall.ECONNREFUSED)))
+	assert.True(t, p.isRetryable(&StatusError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, p.isRetryable(&StatusError{StatusCode: http.StatusUnauthorized}))
+	assert.False(t, p.isRetryable(errors.New("some error")))
+	assert.False(t, p.isRetryable(&Error{Code: ErrCodeInternal, Message: "remote error"}))
+}
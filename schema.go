@@ -0,0 +1,113 @@
+package jrpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is a json schema of method params or result, reflected from go types of typed handlers.
+// Only the subset of json schema needed to describe go types is supported.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`                 // json type, empty for any value
+	Format               string             `json:"format,omitempty"`               // format of string, i.e. date-time
+	Properties           map[string]*Schema `json:"properties,omitempty"`           // fields of struct
+	Items                *Schema            `json:"items,omitempty"`                // elements of slice or array
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"` // values of map
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemaOf reflects json schema of the type, nil type gives nil schema. Interfaces, types with custom
+// json marshaling and recursive references reported as empty schema, i.e. any value.
+func schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return nil
+	}
+	return reflectSchema(t, map[reflect.Type]bool{})
+}
+
+func reflectSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"} // []byte encoded as base64 string
+		}
+		return &Schema{Type: "array", Items: reflectSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: reflectSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &Schema{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		res := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		addFields(res, t, seen)
+		return res
+	default:
+		return &Schema{}
+	}
+}
+
+// addFields adds exported fields of struct type t to properties of res, following json tags.
+// Fields of embedded structs without json name promoted, as encoding/json does.
+func addFields(res *Schema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(res, ft, seen)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		res.Properties[name] = reflectSchema(f.Type, seen)
+	}
+}
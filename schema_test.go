@@ -0,0 +1,68 @@
+package jrpc
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaOf(t *testing.T) {
+	type Embedded struct {
+		E1 string
+	}
+	type node struct {
+		Name     string  `json:"name"`
+		Children []*node `json:"children,omitempty"`
+	}
+	type record struct {
+		Embedded
+		ID       int64             `json:"id"`
+		Score    float64           `json:"score,omitempty"`
+		Active   bool              `json:"active"`
+		Tags     []string          `json:"tags"`
+		Attrs    map[string]int    `json:"attrs"`
+		TS       time.Time         `json:"ts"`
+		Data     []byte            `json:"data"`
+		Raw      json.RawMessage   `json:"raw"`
+		IP       net.IP            `json:"ip"`
+		Any      any               `json:"any"`
+		Ptr      *string           `json:"ptr"`
+		Tree     node              `json:"tree"`
+		Skipped  string            `json:"-"`
+		NoTag    uint8             //nolint
+		Arr      [2]float32        `json:"arr"`
+		unexport string            //nolint
+		Nested   map[string][]bool `json:"nested"`
+	}
+
+	schema := schemaOf(reflect.TypeFor[record]())
+	data, err := json.Marshal(schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"object","properties":{
+		"E1":{"type":"string"},
+		"id":{"type":"integer"},
+		"score":{"type":"number"},
+		"active":{"type":"boolean"},
+		"tags":{"type":"array","items":{"type":"string"}},
+		"attrs":{"type":"object","additionalProperties":{"type":"integer"}},
+		"ts":{"type":"string","format":"date-time"},
+		"data":{"type":"string","format":"byte"},
+		"raw":{},
+		"ip":{"type":"string"},
+		"any":{},
+		"ptr":{"type":"string"},
+		"tree":{"type":"object","properties":{"name":{"type":"string"},"children":{"type":"array","items":{}}}},
+		"NoTag":{"type":"integer"},
+		"arr":{"type":"array","items":{"type":"number"}},
+		"nested":{"type":"object","additionalProperties":{"type":"array","items":{"type":"boolean"}}}
+	}}`, string(data))
+
+	assert.Nil(t, schemaOf(nil))
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "integer"}}, schemaOf(reflect.TypeFor[[]int]()))
+	assert.Equal(t, &Schema{}, schemaOf(reflect.TypeFor[any]()))
+}
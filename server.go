@@ -1,11 +1,15 @@
 package jrpc
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"reflect"
 	"sync"
 	"time"
 
@@ -14,28 +18,52 @@ import (
 	"github.com/go-pkgz/routegroup"
 )
 
-// Server is json-rpc server with an optional basic auth.
-// Auth enforced only if both authUser and authPasswd set, see Auth option.
+// Server is json-rpc server with an optional auth.
+// Auth enforced only if authenticator set, see WithAuthenticator, or both authUser and authPasswd set, see Auth option.
 type Server struct {
 	api string // url path, i.e. "/command" or "/rpc" etc., required
 
-	authUser          string      // basic auth user name, should match Client.AuthUser, optional, no auth if empty
-	authPasswd        string      // basic auth password, should match Client.AuthPasswd, optional, no auth if empty
-	customMiddlewares middlewares // list of custom middlewares, should match array of http.Handler func, optional
+	authUser          string        // basic auth user name, should match Client.AuthUser, optional, no auth if empty
+	authPasswd        string        // basic auth password, should match Client.AuthPasswd, optional, no auth if empty
+	authenticator     Authenticator // resolves the principal of each request, see WithAuthenticator, optional
+	hmac              *hmacVerifier // checks signatures of requests, see WithHMAC, optional
+	customMiddlewares middlewares   // list of custom middlewares, should match array of http.Handler func, optional
+
+	tls struct {
+		certFile, keyFile string      // certificate and key files, see WithTLS
+		config            *tls.Config // tls config, see WithTLSConfig
+	}
 
 	signature signaturePayload // add server signature to server response headers appName, author, version), disable by default
 
 	timeouts Timeouts // values and timeouts for the server
 	limits   limits   // values and limits for the server
 	logger   L        // logger, if nil will default to NoOpLogger
+	jsonrpc2 bool     // speak json-rpc 2.0 instead of the simplified protocol
+
+	notifications struct {
+		size, workers int          // queue size and number of workers, no queue if size is zero
+		queue         *notifyQueue // made on activation
+	}
 
 	funcs struct {
-		m    map[string]ServerFn
+		m    map[string]handlerInfo
 		once sync.Once
 	}
+	interceptors []scopedInterceptor // rpc level interceptors, see WithInterceptors and Intercept
+	authorizer   Authorizer          // per method authorization, see WithAuthorizer
+	discovery    bool                // serve rpc.methods, see WithDiscovery
+	openrpc      bool                // serve OpenRPC document on GET, see WithOpenRPC
+	inflight     inflight            // running calls and drain state, see ShutdownContext
+
+	health struct {
+		enabled bool         // serve /health and /ready, see WithHealthChecks
+		checks  []namedCheck // checks added with AddCheck
+	}
 
 	httpServer struct {
 		*http.Server
+		handler http.Handler // made once by Handler, methods can't be added after that
 		sync.Mutex
 	}
 }
@@ -50,8 +78,9 @@ type Timeouts struct {
 
 // limits includes limits values for a server
 type limits struct {
-	serverThrottle int     // max number of parallel calls for the server
-	clientLimit    float64 // max number of call/sec per client
+	serverThrottle   int     // max number of parallel calls for the server
+	clientLimit      float64 // max number of call/sec per client
+	batchConcurrency int     // max number of calls of a single batch running concurrently
 }
 
 // signaturePayload is the server application info which add to server response headers
@@ -65,6 +94,19 @@ type signaturePayload struct {
 // Implementations provided by consumer and defines response logic.
 type ServerFn func(id uint64, params json.RawMessage) Response
 
+// ContextServerFn handler registered for each method with AddContext or GroupContext.
+// Same as ServerFn, but gets the context of the incoming http request, canceled when the client
+// goes away or CallTimeout reached, and carrying all the values set by middlewares.
+type ContextServerFn func(ctx context.Context, id uint64, params json.RawMessage) Response
+
+// handlerInfo is a registered handler with go types of its params and result, known for typed handlers only
+type handlerInfo struct {
+	fn     ContextServerFn
+	group  string // prefix of the group, for handlers added with Group or GroupContext
+	params reflect.Type
+	result reflect.Type
+}
+
 // middlewares contains list of custom middlewares which user can attach to server
 type middlewares []func(http.Handler) http.Handler
 
@@ -100,12 +142,55 @@ func (s *Server) Run(port int) error {
 	return s.serve(ln)
 }
 
-// activate makes http server with all the middlewares and the dispatch handler.
+// Serve runs http server on the provided listener, blocks until Shutdown called or the server failed.
+// The listener closed on return.
+func (s *Server) Serve(l net.Listener) error {
+
+	if len(s.funcs.m) == 0 {
+		_ = l.Close()
+		return fmt.Errorf("nothing mapped for dispatch, Add has to be called prior to Serve")
+	}
+
+	s.activate()
+	return s.serve(l)
+}
+
+// activate makes http server with all the middlewares and the dispatch handler, see Handler.
 // after this call Add won't accept new methods.
 func (s *Server) activate() {
+	h := s.Handler()
+	s.httpServer.Lock()
+	s.httpServer.Server = &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: s.timeouts.ReadHeaderTimeout,
+		WriteTimeout:      s.timeouts.WriteTimeout,
+		IdleTimeout:       s.timeouts.IdleTimeout,
+	}
+	s.httpServer.Unlock()
+}
+
+// Handler returns http handler with all the middlewares and the dispatch of calls, without starting a listener.
+// It can be mounted into an existing router on the api path, i.e. mux.Handle("/command", srv.Handler()), with
+// ports, TLS and lifecycle owned by the caller's http server. The handler made once, on the first call of Handler,
+// Run or Serve, and methods can't be added after that. Shutdown of the server doesn't stop the caller's http server,
+// but still processes queued notifications.
+func (s *Server) Handler() http.Handler {
+	s.httpServer.Lock()
+	defer s.httpServer.Unlock()
+	if s.httpServer.handler == nil {
+		s.httpServer.handler = s.makeHandler()
+	}
+	return s.httpServer.handler
+}
 
-	if s.authUser == "" || s.authPasswd == "" {
-		s.logger.Logf("[WARN] extension server runs without auth, both user and password have to be set to enable it")
+// makeHandler makes router with all the middlewares and the dispatch handler, called with httpServer locked
+func (s *Server) makeHandler() http.Handler {
+
+	if s.authenticator == nil && s.authUser != "" && s.authPasswd != "" {
+		s.authenticator = BasicAuth{s.authUser: s.authPasswd}
+	}
+	if s.authenticator == nil && s.hmac == nil {
+		s.logger.Logf("[WARN] extension server runs without auth, both user and password or authenticator have to be set to enable it")
 	}
 
 	router := routegroup.New(http.NewServeMux())
@@ -115,6 +200,9 @@ func (s *Server) activate() {
 	}
 
 	router.Use(rest.RealIP, rest.Ping, rest.Recoverer(s.logger))
+	if s.health.enabled {
+		router.Use(s.healthMiddleware())
+	}
 
 	if s.signature.version != "" || s.signature.author != "" || s.signature.appName != "" {
 		router.Use(rest.AppInfo(s.signature.appName, s.signature.author, s.signature.version))
@@ -131,21 +219,27 @@ func (s *Server) activate() {
 		router.Use(rateLimitByIP(s.limits.clientLimit))
 	}
 
-	router.Use(rest.NoCache)
-	router.Use(s.basicAuth)
+	router.Use(rest.NoCache, peerCert)
+	router.Use(s.auth)
+	if s.hmac != nil {
+		router.Use(s.hmac.middleware(s.logger))
+	}
 	for _, mw := range s.customMiddlewares {
 		router.Use(mw)
 	}
+	if s.discovery {
+		s.register(discoveryMethod, handlerInfo{fn: s.discoveryHndl})
+	}
+	s.intercept()
 	router.HandleFunc("POST "+s.api, s.handler)
+	if s.openrpc {
+		router.HandleFunc("GET "+s.api, s.openRPCHndl)
+	}
 
-	s.httpServer.Lock()
-	s.httpServer.Server = &http.Server{
-		Handler:           router,
-		ReadHeaderTimeout: s.timeouts.ReadHeaderTimeout,
-		WriteTimeout:      s.timeouts.WriteTimeout,
-		IdleTimeout:       s.timeouts.IdleTimeout,
+	if s.notifications.size > 0 {
+		s.notifications.queue = newNotifyQueue(s.notifications.size, s.notifications.workers)
 	}
-	s.httpServer.Unlock()
+	return router
 }
 
 // serve runs activated http server on the provided listener
@@ -158,36 +252,64 @@ func (s *Server) serve(l net.Listener) error {
 		return fmt.Errorf("server is not activated")
 	}
 
+	cfg, err := s.tlsConfig()
+	if err != nil {
+		_ = l.Close()
+		return err
+	}
+	if cfg != nil {
+		s.logger.Logf("[INFO] listen with tls on %s", l.Addr())
+		srv.TLSConfig = cfg
+		return srv.ServeTLS(l, "", "")
+	}
+
 	s.logger.Logf("[INFO] listen on %s", l.Addr())
 	return srv.Serve(l)
 }
 
-// Shutdown http server
+// Shutdown http server, draining calls in flight for up to 5 seconds, see ShutdownContext.
+// Queued notifications, if any, processed before return.
 func (s *Server) Shutdown() error {
-	s.httpServer.Lock()
-	defer s.httpServer.Unlock()
-	if s.httpServer.Server == nil {
-		return fmt.Errorf("http server is not running")
-	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	return s.httpServer.Shutdown(ctx)
+	return s.ShutdownContext(ctx)
 }
 
 // Add method handler. Handler will be called on matching method (Request.Method)
 func (s *Server) Add(method string, fn ServerFn) {
+	s.add(method, handlerInfo{fn: fn.withContext()})
+}
+
+// withContext converts ServerFn to ContextServerFn ignoring the context
+func (fn ServerFn) withContext() ContextServerFn {
+	return func(_ context.Context, id uint64, params json.RawMessage) Response {
+		return fn(id, params)
+	}
+}
+
+// AddContext method handler with context. Handler will be called on matching method (Request.Method)
+func (s *Server) AddContext(method string, fn ContextServerFn) {
+	s.add(method, handlerInfo{fn: fn})
+}
+
+// add registers handler for the method, ignored if the server already activated
+func (s *Server) add(method string, h handlerInfo) {
 	s.httpServer.Lock()
 	defer s.httpServer.Unlock()
-	if s.httpServer.Server != nil {
+	if s.httpServer.handler != nil {
 		s.logger.Logf("[WARN] ignored method %s, can't be added to activated server", method)
 		return
 	}
+	s.register(method, h)
+}
 
+// register adds handler to the method table, called with httpServer locked
+func (s *Server) register(method string, h handlerInfo) {
 	s.funcs.once.Do(func() {
-		s.funcs.m = map[string]ServerFn{}
+		s.funcs.m = map[string]handlerInfo{}
 	})
 
-	s.funcs.m[method] = fn
+	s.funcs.m[method] = h
 	s.logger.Logf("[INFO] add handler for %s", method)
 }
 
@@ -197,54 +319,262 @@ type HandlersGroup map[string]ServerFn
 // Group of handlers with common prefix, match on group.method
 func (s *Server) Group(prefix string, m HandlersGroup) {
 	for k, v := range m {
-		s.Add(prefix+"."+k, v)
+		s.add(prefix+"."+k, handlerInfo{fn: v.withContext(), group: prefix})
+	}
+}
+
+// ContextHandlersGroup alias for map of context handlers
+type ContextHandlersGroup map[string]ContextServerFn
+
+// GroupContext of context handlers with common prefix, match on group.method
+func (s *Server) GroupContext(prefix string, m ContextHandlersGroup) {
+	for k, v := range m {
+		s.add(prefix+"."+k, handlerInfo{fn: v, group: prefix})
 	}
 }
 
-// handler is http handler multiplexing calls by req.Method
+// request is a single call as decoded by the server in the default mode, nil ID means notification
+type request struct {
+	ID     *uint64          `json:"id"`
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
+}
+
+// handler is http handler multiplexing calls by req.Method. Notifications answered with 204 and no body.
 func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
-	req := struct {
-		ID     uint64           `json:"id"`
-		Method string           `json:"method"`
-		Params *json.RawMessage `json:"params"`
-	}{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rest.SendErrorJSON(w, r, s.logger, http.StatusBadRequest, err, "can't read request")
+		return
+	}
+
+	if isBatch(body) {
+		s.batchHandler(w, r, body)
+		return
+	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if s.jsonrpc2 {
+		resp, ok := s.process2(r.Context(), body)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rest.RenderJSON(w, resp)
+		return
+	}
+
+	req := request{}
+	if err = json.Unmarshal(body, &req); err != nil {
 		rest.SendErrorJSON(w, r, s.logger, http.StatusBadRequest, err, req.Method)
 		return
 	}
-	fn, ok := s.funcs.m[req.Method]
-	if !ok {
-		rest.SendErrorJSON(w, r, s.logger, http.StatusNotImplemented, fmt.Errorf("unsupported method"), req.Method)
+	if _, ok := s.funcs.m[req.Method]; !ok {
+		// 501 kept for old clients, the body carries the code for the ones decoding it
+		s.logger.Logf("[WARN] unsupported method %s from %s", req.Method, r.RemoteAddr)
+		var id uint64
+		if req.ID != nil {
+			id = *req.ID
+		}
+		_ = rest.EncodeJSON(w, http.StatusNotImplemented, EncodeResponse(id, nil, NewError(ErrCodeMethodNotFound, "unsupported method")))
+		return
+	}
+
+	if req.ID == nil {
+		s.notify(r.Context(), req.Method, req.params())
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rest.RenderJSON(w, s.call(r.Context(), req.Method, *req.ID, req.params()))
+}
+
+// batchHandler runs all the calls of the batch and responds with the list of responses.
+// Calls run one by one, or concurrently if enabled with WithBatchConcurrency.
+// Notifications have no responses, batch of notifications only answered with 204 and no body.
+func (s *Server) batchHandler(w http.ResponseWriter, r *http.Request, body []byte) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		if s.jsonrpc2 {
+			rest.RenderJSON(w, errorResponse2(NewError(ErrCodeParse, "parse error"), nullID))
+			return
+		}
+		rest.SendErrorJSON(w, r, s.logger, http.StatusBadRequest, err, "can't decode batch")
+		return
+	}
+	if len(items) == 0 {
+		if s.jsonrpc2 {
+			rest.RenderJSON(w, errorResponse2(NewError(ErrCodeInvalidRequest, "invalid request: empty batch"), nullID))
+			return
+		}
+		rest.SendErrorJSON(w, r, s.logger, http.StatusBadRequest, fmt.Errorf("no calls"), "empty batch")
+		return
+	}
+
+	res := make([]any, len(items))
+	process := func(i int) {
+		var resp any
+		var ok bool
+		if s.jsonrpc2 {
+			resp, ok = s.process2(r.Context(), items[i])
+		} else {
+			resp, ok = s.process(r.Context(), items[i])
+		}
+		if ok {
+			res[i] = resp
+		}
+	}
+
+	if s.limits.batchConcurrency <= 1 {
+		for i := range items {
+			process(i)
+		}
+	} else {
+		var wg sync.WaitGroup
+		sema := make(chan struct{}, s.limits.batchConcurrency)
+		for i := range items {
+			wg.Add(1)
+			sema <- struct{}{}
+			go func() {
+				defer func() { <-sema; wg.Done() }()
+				process(i)
+			}()
+		}
+		wg.Wait()
+	}
+
+	responses := make([]any, 0, len(res))
+	for _, resp := range res {
+		if resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
 		return
+	}
+	rest.RenderJSON(w, responses)
+}
 
+// process runs a single call of the batch in the default mode. Unlike handler, broken call and unknown
+// method reported in the response, as the other calls of the batch still have to be answered.
+// Returns false for notifications, they have no response.
+func (s *Server) process(ctx context.Context, body []byte) (Response, bool) {
+	req := request{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return EncodeResponse(0, nil, NewError(ErrCodeInvalidRequest, "invalid request: "+err.Error())), true
 	}
+	if req.ID == nil {
+		s.notify(ctx, req.Method, req.params())
+		return Response{}, false
+	}
+	return s.call(ctx, req.Method, *req.ID, req.params()), true
+}
 
-	params := json.RawMessage{}
-	if req.Params != nil {
-		params = *req.Params
+// process2 is json-rpc 2.0 version of process, used for both single and batch calls. All the errors,
+// including broken json and unknown method, sent as error objects with 200 status, as required by the spec.
+// Returns false for notifications, they have no response, even if failed.
+func (s *Server) process2(ctx context.Context, body []byte) (response2, bool) {
+	if !json.Valid(body) {
+		return errorResponse2(NewError(ErrCodeParse, "parse error"), nullID), true
 	}
 
-	rest.RenderJSON(w, fn(req.ID, params))
+	req := request2{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return errorResponse2(NewError(ErrCodeInvalidRequest, "invalid request: "+err.Error()), nullID), true
+	}
+	if err := req.validate(); err != nil {
+		return errorResponse2(err.(*Error), req.responseID()), true
+	}
+
+	if req.isNotification() {
+		s.notify(ctx, req.Method, req.Params)
+		return response2{}, false
+	}
+	return encodeResponse2(s.call(ctx, req.Method, req.numericID(), req.Params), req.responseID()), true
 }
 
-// basicAuth middleware, enabled only if both authUser and authPasswd set to non-empty values.
-// with either of them empty every request passes through unauthenticated.
-func (s *Server) basicAuth(h http.Handler) http.Handler {
+// notify runs handler for the notification. The handler runs in the background if notification queue
+// enabled with WithNotifyQueue, and synchronously if not enabled or the queue is full.
+// Result of the handler dropped, the error only logged.
+func (s *Server) notify(ctx context.Context, method string, params json.RawMessage) {
+	if !s.inflight.enter() {
+		s.logger.Logf("[WARN] notification %s rejected, server is shutting down", method)
+		return
+	}
+	run := func(ctx context.Context) {
+		defer s.inflight.leave()
+		if resp := s.invoke(ctx, method, 0, params); resp.Error != "" {
+			s.logger.Logf("[WARN] notification %s failed: %s", method, resp.Error)
+		}
+	}
+
+	if q := s.notifications.queue; q != nil {
+		bgCtx := context.WithoutCancel(ctx) // the request context canceled as soon as 204 sent
+		if q.push(func() { run(bgCtx) }) {
+			return
+		}
+		s.logger.Logf("[DEBUG] notification queue is full, run %s synchronously", method)
+	}
+	run(ctx)
+}
+
+// call runs handler registered for the method, rejected with ErrShuttingDown while the server is draining
+func (s *Server) call(ctx context.Context, method string, id uint64, params json.RawMessage) Response {
+	if !s.inflight.enter() {
+		return EncodeResponse(id, nil, ErrShuttingDown)
+	}
+	defer s.inflight.leave()
+	return s.invoke(ctx, method, id, params)
+}
+
+// invoke runs handler registered for the method, unknown method reported as Error with ErrCodeMethodNotFound.
+// The handler context canceled with ErrShuttingDown cause if the shutdown forced.
+func (s *Server) invoke(ctx context.Context, method string, id uint64, params json.RawMessage) Response {
+	h, ok := s.funcs.m[method]
+	if !ok {
+		return EncodeResponse(id, nil, NewError(ErrCodeMethodNotFound, "unsupported method"))
+	}
+	if params == nil || string(params) == "null" {
+		params = json.RawMessage{}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	defer s.inflight.track(cancel)()
+	return h.fn(context.WithValue(ctx, methodCtxKey{}, method), id, params)
+}
+
+// params returns request params, empty if not set
+func (r request) params() json.RawMessage {
+	if r.Params == nil {
+		return json.RawMessage{}
+	}
+	return *r.Params
+}
+
+// isBatch checks if body is a json array, i.e. the batch of calls
+func isBatch(body []byte) bool {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// auth middleware, enabled only if authenticator set with WithAuthenticator or both authUser and authPasswd
+// set with Auth. The principal of authenticated request put to the context, see PrincipalFromContext.
+func (s *Server) auth(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if s.authUser == "" || s.authPasswd == "" {
+		if s.authenticator == nil {
 			h.ServeHTTP(w, r)
 			return
 		}
 
-		user, pass, ok := r.BasicAuth()
-		if user != s.authUser || pass != s.authPasswd || !ok {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+		principal, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			if isBasic(s.authenticator) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="Restricted"`)
+			}
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		h.ServeHTTP(w, r)
+		h.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
 	})
 }
 
@@ -259,8 +589,9 @@ func getDefaultTimeouts() Timeouts {
 // timeout middleware limits the time allowed for the call, responds with 503 and drops
 // the late handler writes if the deadline reached
 func timeout(dt time.Duration) func(http.Handler) http.Handler {
+	msg := fmt.Sprintf(`{"error":"call timeout","error_code":%d}`, ErrCodeTimeout)
 	return func(h http.Handler) http.Handler {
-		return http.TimeoutHandler(h, dt, `{"error":"call timeout"}`)
+		return http.TimeoutHandler(h, dt, msg)
 	}
 }
 
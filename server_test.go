@@ -2,6 +2,7 @@ package jrpc
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -181,6 +184,60 @@ func TestServerErrReturn(t *testing.T) {
 	assert.EqualError(t, err, "some error")
 }
 
+func TestServerStructuredErrReturn(t *testing.T) {
+	s := NewServer("/v1/cmd")
+
+	s.Add("coded", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, &Error{Code: 404, Message: "not found", Data: json.RawMessage(`{"key":"abc"}`)})
+	})
+	s.Add("wrapped", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, fmt.Errorf("load failed: %w", NewError(500, "db down")))
+	})
+	s.Add("plain", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, fmt.Errorf("some error"))
+	})
+
+	url := startServer(t, s)
+
+	t.Run("wire format", func(t *testing.T) {
+		b := bytes.Buffer{}
+		require.NoError(t, json.NewEncoder(&b).Encode(Request{Method: "coded", ID: 7}))
+		resp, err := http.Post(url+"/v1/cmd", "application/json", &b)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"error":"not found","error_code":404,"error_data":{"key":"abc"},"id":7}`+"\n", string(data))
+	})
+
+	c := Client{API: url + "/v1/cmd", Client: http.Client{}}
+
+	t.Run("coded", func(t *testing.T) {
+		_, err := c.Call("coded")
+		require.EqualError(t, err, "not found")
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, 404, rpcErr.Code)
+		assert.JSONEq(t, `{"key":"abc"}`, string(rpcErr.Data))
+	})
+
+	t.Run("wrapped", func(t *testing.T) {
+		_, err := c.Call("wrapped")
+		require.EqualError(t, err, "load failed: db down")
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, 500, rpcErr.Code)
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		_, err := c.Call("plain")
+		require.EqualError(t, err, "some error")
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, 0, rpcErr.Code)
+	})
+}
+
 func TestServerGroup(t *testing.T) {
 	s := NewServer("/v1/cmd")
 
@@ -205,6 +262,77 @@ func TestServerGroup(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestServerAddContext(t *testing.T) {
+	type ctxKey struct{}
+	mw := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "from middleware")))
+		})
+	}
+	s := NewServer("/v1/cmd", WithMiddlewares(mw))
+
+	s.AddContext("fn1", func(ctx context.Context, id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, ctx.Value(ctxKey{}), nil)
+	})
+	s.GroupContext("pre", ContextHandlersGroup{
+		"fn2": func(ctx context.Context, id uint64, params json.RawMessage) Response {
+			var arg string
+			if err := json.Unmarshal(params, &arg); err != nil {
+				return Response{Error: err.Error()}
+			}
+			return EncodeResponse(id, arg+" "+ctx.Value(ctxKey{}).(string), nil)
+		},
+	})
+	s.Add("fn3", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, "plain", nil)
+	})
+
+	url := startServer(t, s)
+	c := Client{API: url + "/v1/cmd", Client: http.Client{}}
+
+	for _, tc := range []struct {
+		method string
+		args   []any
+		res    string
+	}{
+		{method: "fn1", res: "from middleware"},
+		{method: "pre.fn2", args: []any{"arg"}, res: "arg from middleware"},
+		{method: "fn3", res: "plain"},
+	} {
+		r, err := c.Call(tc.method, tc.args...)
+		require.NoError(t, err, tc.method)
+		val := ""
+		require.NoError(t, json.Unmarshal(*r.Result, &val))
+		assert.Equal(t, tc.res, val, tc.method)
+	}
+}
+
+func TestServerAddContextCanceled(t *testing.T) {
+	s := NewServer("/v1/cmd", WithTimeouts(Timeouts{
+		ReadHeaderTimeout: time.Second,
+		WriteTimeout:      5 * time.Second,
+		IdleTimeout:       time.Second,
+		CallTimeout:       50 * time.Millisecond,
+	}))
+
+	canceled := make(chan error, 1)
+	s.AddContext("slow", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		select {
+		case <-ctx.Done():
+			canceled <- ctx.Err()
+		case <-time.After(time.Second):
+			canceled <- nil
+		}
+		return EncodeResponse(id, "too late", nil)
+	})
+
+	url := startServer(t, s)
+	c := Client{API: url + "/v1/cmd", Client: http.Client{}}
+	_, err := c.Call("slow")
+	assert.EqualError(t, err, "bad status 503 Service Unavailable for slow")
+	assert.ErrorIs(t, <-canceled, context.DeadlineExceeded)
+}
+
 func TestServerAddLate(t *testing.T) {
 	s := NewServer("/v1/cmd")
 
@@ -225,6 +353,56 @@ func TestServerAddLate(t *testing.T) {
 	assert.EqualError(t, err, "bad status 501 Not Implemented for fn2")
 }
 
+func TestServerHandler(t *testing.T) {
+	s := NewServer("/v1/cmd", Auth("user", "passwd"), WithNotifyQueue(10, 1))
+	s.Add("fn1", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, "res1", nil)
+	})
+	var notified atomic.Int32
+	s.Add("event", func(id uint64, params json.RawMessage) Response {
+		notified.Add(1)
+		return Response{}
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/other", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("other")) })
+	h := s.Handler()
+	mux.Handle("/v1/cmd", h)
+	assert.Equal(t, fmt.Sprintf("%p", h), fmt.Sprintf("%p", s.Handler()), "handler made once")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// too late, ignored once the handler made
+	s.Add("fn2", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, "res2", nil)
+	})
+
+	c := Client{API: ts.URL + "/v1/cmd", AuthUser: "user", AuthPasswd: "passwd"}
+	v, err := Invoke[string](context.Background(), &c, "fn1")
+	require.NoError(t, err)
+	assert.Equal(t, "res1", v)
+	_, err = c.Call("fn2")
+	assert.EqualError(t, err, "bad status 501 Not Implemented for fn2")
+	_, err = (&Client{API: ts.URL + "/v1/cmd"}).Call("fn1")
+	assert.EqualError(t, err, "bad status 401 Unauthorized for fn1")
+	require.NoError(t, c.Notify("event"))
+
+	resp, err := http.Get(ts.URL + "/other")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, "other", string(body))
+
+	// shutdown processes queued notifications, the caller's http server keeps running
+	require.NoError(t, s.Shutdown())
+	assert.Equal(t, int32(1), notified.Load())
+	resp, err = http.Get(ts.URL + "/other")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
 func TestServerNoHandlers(t *testing.T) {
 	s := NewServer("/v1/cmd", Auth("user", "passwd"))
 	assert.EqualError(t, s.Run(0), "nothing mapped for dispatch, Add has to be called prior to Run")
@@ -466,9 +644,7 @@ func TestRateLimitByIP(t *testing.T) {
 func startServer(t *testing.T, s *Server) string {
 	t.Helper()
 
-	l, err := net.Listen("tcp", "127.0.0.1:0")
-	require.NoError(t, err)
-
+	l := listen(t)
 	s.activate()
 	done := make(chan error, 1)
 	go func() { done <- s.serve(l) }()
@@ -481,6 +657,14 @@ func startServer(t *testing.T, s *Server) string {
 	return "http://" + l.Addr().String()
 }
 
+// listen binds a random local port
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	return l
+}
+
 func TestServerRunFailedToListen(t *testing.T) {
 	l, err := net.Listen("tcp", ":0") //nolint:gosec // has to bind the same way Run does to collide with it
 	require.NoError(t, err)
@@ -553,6 +737,126 @@ func TestServerCallTimeout(t *testing.T) {
 
 		data, err := io.ReadAll(resp.Body)
 		require.NoError(t, err)
-		assert.Equal(t, `{"error":"call timeout"}`, string(data))
+		assert.Equal(t, `{"error":"call timeout","error_code":-32000}`, string(data))
 	})
 }
+
+func TestServerBatch(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	Handle(s, "double", func(_ context.Context, v int) (int, error) {
+		return v * 2, nil
+	})
+	url := startServer(t, s)
+
+	tbl := []struct {
+		name   string
+		req    string
+		status int
+		resp   string
+	}{
+		{name: "batch", req: `[{"method":"double","params":1,"id":1},{"method":"double","params":2,"id":2}]`, status: 200,
+			resp: `[{"result":2,"id":1},{"result":4,"id":2}]`},
+		{name: "unknown method and bad params", req: `[{"method":"blah","id":1},{"method":"double","params":"x","id":2}]`, status: 200,
+			resp: `[{"error":"unsupported method","error_code":-32601,"id":1},` +
+				`{"error":"invalid params: json: cannot unmarshal string into Go value of type int","error_code":-32602,"id":2}]`},
+		{name: "broken call", req: ` [{"method":"double","params":1,"id":1}, 123]`, status: 200,
+			resp: `[{"result":2,"id":1},` +
+				`{"error":"invalid request: json: cannot unmarshal number into Go value of type jrpc.request","error_code":-32600,"id":0}]`},
+		{name: "empty batch", req: `[]`, status: 400, resp: `{"error":"empty batch"}`},
+		{name: "broken batch", req: `[{"method":"double"`, status: 400},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(url+"/v1/cmd", "application/json", bytes.NewBufferString(tt.req))
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			assert.Equal(t, tt.status, resp.StatusCode)
+			if tt.resp == "" {
+				return
+			}
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resp+"\n", string(data))
+		})
+	}
+}
+
+func TestServerBatchJSONRPC2(t *testing.T) {
+	s := NewServer("/v1/cmd", WithJSONRPC2())
+	Handle(s, "double", func(_ context.Context, v int) (int, error) {
+		return v * 2, nil
+	})
+	url := startServer(t, s)
+
+	tbl := []struct {
+		name string
+		req  string
+		resp string
+	}{
+		{name: "batch", req: `[{"jsonrpc":"2.0","method":"double","params":[1],"id":"a"},{"jsonrpc":"2.0","method":"double","params":[2],"id":2}]`,
+			resp: `[{"jsonrpc":"2.0","result":2,"id":"a"},{"jsonrpc":"2.0","result":4,"id":2}]`},
+		{name: "invalid calls", req: `[1,{"jsonrpc":"2.0","method":"blah","id":2}]`,
+			resp: `[{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: json: cannot unmarshal number into Go value of type jrpc.request2"},"id":null},` +
+				`{"jsonrpc":"2.0","error":{"code":-32601,"message":"unsupported method"},"id":2}]`},
+		{name: "empty batch", req: `[]`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: empty batch"},"id":null}`},
+		{name: "broken batch", req: `[{"jsonrpc":"2.0","method":"double"`,
+			resp: `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":null}`},
+	}
+
+	for _, tt := range tbl {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(url+"/v1/cmd", "application/json", bytes.NewBufferString(tt.req))
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			data, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.resp+"\n", string(data))
+		})
+	}
+}
+
+func TestServerBatchConcurrency(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		concurrency int
+		maxActive   int32
+	}{
+		{name: "sequential", concurrency: 0, maxActive: 1},
+		{name: "concurrent", concurrency: 3, maxActive: 3},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var active, maxActive atomic.Int32
+			s := NewServer("/v1/cmd", WithBatchConcurrency(tt.concurrency))
+			Handle(s, "slow", func(_ context.Context, v int) (int, error) {
+				n := active.Add(1)
+				defer active.Add(-1)
+				for {
+					m := maxActive.Load()
+					if n <= m || maxActive.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				return v, nil
+			})
+			url := startServer(t, s)
+
+			calls := make([]BatchCall, 6)
+			for i := range calls {
+				calls[i] = BatchCall{Method: "slow", Args: []any{i}}
+			}
+			c := Client{API: url + "/v1/cmd"}
+			res, err := c.CallBatch(context.Background(), calls...)
+			require.NoError(t, err)
+			require.Len(t, res, 6)
+			for i, r := range res {
+				require.NoError(t, r.Err())
+				assert.JSONEq(t, strconv.Itoa(i), string(*r.Result))
+			}
+			assert.Equal(t, tt.maxActive, maxActive.Load())
+		})
+	}
+}
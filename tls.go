@@ -0,0 +1,117 @@
+package jrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ErrNoClientCert returned by ClientCertAuth for requests without verified client certificate
+var ErrNoClientCert = errors.New("no verified client certificate")
+
+// ClientCertAuth is Authenticator of mutual TLS, the principal is the common name of the verified client
+// certificate. Requires the server with TLS config verifying client certificates, see WithTLSConfig.
+type ClientCertAuth struct{}
+
+// Authenticate returns the common name of the verified client certificate
+func (ClientCertAuth) Authenticate(r *http.Request) (string, error) {
+	cert := peerCertificate(r)
+	if cert == nil || cert.Subject.CommonName == "" {
+		return "", ErrNoClientCert
+	}
+	return cert.Subject.CommonName, nil
+}
+
+type peerCertCtxKey struct{}
+
+// PeerCertificateFromContext returns the verified client certificate of the call, available for handlers
+// and interceptors. Returns nil if the server doesn't verify client certificates or the request has none.
+func PeerCertificateFromContext(ctx context.Context) *x509.Certificate {
+	cert, _ := ctx.Value(peerCertCtxKey{}).(*x509.Certificate)
+	return cert
+}
+
+// peerCertificate returns the leaf of the first verified chain of the request, nil if not verified
+func peerCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// peerCert middleware puts the verified client certificate to the request context
+func peerCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cert := peerCertificate(r); cert != nil {
+			r = r.WithContext(context.WithValue(r.Context(), peerCertCtxKey{}, cert))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tlsConfig makes tls config of the server from WithTLSConfig and certificate files of WithTLS,
+// nil if TLS is not enabled
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	if s.tls.config == nil && s.tls.certFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.tls.config != nil {
+		cfg = s.tls.config.Clone()
+	}
+	if s.tls.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.tls.certFile, s.tls.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load tls certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	return cfg, nil
+}
+
+// LoadCertPool makes certificate pool of pem encoded certificates from files, i.e. to pin CA of the server
+// for Client or to verify client certificates with Server, see ClientTLSConfig and WithTLSConfig
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, f := range files {
+		pem, err := os.ReadFile(f) //nolint:gosec // file names set by the caller
+		if err != nil {
+			return nil, fmt.Errorf("can't read certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", f)
+		}
+	}
+	return pool, nil
+}
+
+// ClientTLSConfig makes tls config for Client trusting the server certificates issued by CA from caFile only,
+// and presenting the client certificate from certFile and keyFile for mutual TLS. Empty caFile keeps system roots,
+// empty certFile and keyFile skip the client certificate. Use it as the transport config of Client.Client:
+//
+//	cfg, err := jrpc.ClientTLSConfig("ca.pem", "client.pem", "client-key.pem")
+//	client := jrpc.Client{API: "https://127.0.0.1:8443/command",
+//		Client: http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}}
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pool, err := LoadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,205 @@
+package jrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	s := NewServer("/v1/cmd", WithTLS(pki.cert(t, "server", true)))
+	s.AddContext("peer", func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+		return EncodeResponse(id, PeerCertificateFromContext(ctx) == nil, nil)
+	})
+	url := startTLSServer(t, s)
+
+	cfg, err := ClientTLSConfig(pki.caFile, "", "")
+	require.NoError(t, err)
+	c := &Client{API: url + "/v1/cmd", Client: http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}}
+	noPeer, err := Invoke[bool](context.Background(), c, "peer")
+	require.NoError(t, err)
+	assert.True(t, noPeer)
+
+	// server certificate not trusted without pinned CA
+	_, err = (&Client{API: url + "/v1/cmd", Client: http.Client{Transport: &http.Transport{}}}).Call("peer")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+
+	// plain http rejected
+	resp, err := http.Post("http://"+url[len("https://"):]+"/v1/cmd", "application/json", http.NoBody)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	other := newTestPKI(t)
+
+	clientCAs, err := LoadCertPool(pki.caFile)
+	require.NoError(t, err)
+	s := NewServer("/v1/cmd", WithTLS(pki.cert(t, "server", true)),
+		WithTLSConfig(&tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs, MinVersion: tls.VersionTLS12}),
+		WithAuthenticator(ClientCertAuth{}), WithAuthorizer(AllowList{"reader": {"store.load"}, "writer": {"store.*"}}))
+	s.GroupContext("store", ContextHandlersGroup{
+		"load": func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+			return EncodeResponse(id, PeerCertificateFromContext(ctx).Subject.CommonName, nil)
+		},
+		"save": func(ctx context.Context, id uint64, _ json.RawMessage) Response {
+			return EncodeResponse(id, PrincipalFromContext(ctx), nil)
+		},
+	})
+	url := startTLSServer(t, s)
+
+	client := func(certFile, keyFile string) *Client {
+		cfg, err := ClientTLSConfig(pki.caFile, certFile, keyFile)
+		require.NoError(t, err)
+		return &Client{API: url + "/v1/cmd", Client: http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}}
+	}
+	ctx := context.Background()
+
+	reader := client(pki.cert(t, "reader", false))
+	v, err := Invoke[string](ctx, reader, "store.load")
+	require.NoError(t, err)
+	assert.Equal(t, "reader", v)
+	_, err = reader.Call("store.save")
+	assert.EqualError(t, err, "reader not allowed to call store.save")
+
+	v, err = Invoke[string](ctx, client(pki.cert(t, "writer", false)), "store.save")
+	require.NoError(t, err)
+	assert.Equal(t, "writer", v)
+
+	// no client certificate, or issued by untrusted CA
+	_, err = client("", "").Call("store.load")
+	require.Error(t, err)
+	_, err = client(other.cert(t, "reader", false)).Call("store.load")
+	require.Error(t, err)
+}
+
+func TestServerTLSFailed(t *testing.T) {
+	s := NewServer("/v1/cmd", WithTLS("/no/such/cert.pem", "/no/such/key.pem"))
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return Response{} })
+	s.activate()
+	err := s.serve(listen(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't load tls certificate")
+}
+
+func TestClientCertAuth(t *testing.T) {
+	req, err := http.NewRequest("POST", "/", http.NoBody)
+	require.NoError(t, err)
+	_, err = ClientCertAuth{}.Authenticate(req)
+	assert.ErrorIs(t, err, ErrNoClientCert)
+
+	req.TLS = &tls.ConnectionState{}
+	_, err = ClientCertAuth{}.Authenticate(req)
+	assert.ErrorIs(t, err, ErrNoClientCert)
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "svc"}}}}
+	principal, err := ClientCertAuth{}.Authenticate(req)
+	require.NoError(t, err)
+	assert.Equal(t, "svc", principal)
+
+	assert.Nil(t, PeerCertificateFromContext(context.Background()))
+}
+
+func TestLoadCertPoolFailed(t *testing.T) {
+	_, err := LoadCertPool("/no/such/ca.pem")
+	assert.ErrorContains(t, err, "can't read certificate")
+
+	f := filepath.Join(t.TempDir(), "bad.pem")
+	require.NoError(t, os.WriteFile(f, []byte("not a pem"), 0o600))
+	_, err = LoadCertPool(f)
+	assert.ErrorContains(t, err, "no certificates in")
+
+	_, err = ClientTLSConfig(f, "", "")
+	require.Error(t, err)
+	_, err = ClientTLSConfig("", f, f)
+	assert.ErrorContains(t, err, "can't load client certificate")
+}
+
+// startTLSServer is startServer for the server with TLS, returns https url
+func startTLSServer(t *testing.T, s *Server) string {
+	t.Helper()
+	url := startServer(t, s)
+	return "https://" + url[len("http://"):]
+}
+
+// testPKI is CA issuing certificates for tests, all files written to the temp dir
+type testPKI struct {
+	dir    string
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caFile string
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	p := &testPKI{dir: t.TempDir(), ca: ca, caKey: key}
+	p.caFile = p.write(t, "ca.pem", "CERTIFICATE", der)
+	return p
+}
+
+// cert issues certificate with common name, for server or client use, and returns files of certificate and key
+func (p *testPKI) cert(t *testing.T, cn string, server bool) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.caKey)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return p.write(t, cn+".pem", "CERTIFICATE", der), p.write(t, cn+"-key.pem", "EC PRIVATE KEY", keyDer)
+}
+
+func (p *testPKI) write(t *testing.T, name, typ string, der []byte) string {
+	t.Helper()
+	f := filepath.Join(p.dir, name)
+	require.NoError(t, os.WriteFile(f, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+	return f
+}
@@ -0,0 +1,66 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNoResult returned by Invoke if the remote call succeeded but the response has no result or a null one
+var ErrNoResult = errors.New("no result")
+
+// Handle adds typed method handler. Params decoded to P and passed to fn, returned R and error encoded
+// to Response, so fn doesn't deal with json at all. Missing params leave P with zero value.
+// Types of params and result reported by discovery, see WithDiscovery.
+func Handle[P, R any](s *Server, method string, fn func(ctx context.Context, p P) (R, error)) {
+	s.add(method, handlerInfo{
+		fn: func(ctx context.Context, id uint64, params json.RawMessage) Response {
+			var p P
+			if err := DecodeParams(params, &p); err != nil {
+				return EncodeResponse(id, nil, err)
+			}
+			res, err := fn(ctx, p)
+			return EncodeResponse(id, res, err)
+		},
+		params: reflect.TypeFor[P](),
+		result: reflect.TypeFor[R](),
+	})
+}
+
+// Invoke calls remote method with given args, see Client.CallContext, and decodes the result to R.
+// Missing or null result reported as ErrNoResult.
+func Invoke[R any](ctx context.Context, c *Client, method string, args ...any) (R, error) {
+	var res R
+	resp, err := c.CallContext(ctx, method, args...)
+	if err != nil {
+		return res, err
+	}
+	if resp.Result == nil || string(*resp.Result) == "null" {
+		return res, fmt.Errorf("%w for %s", ErrNoResult, method)
+	}
+	if err = json.Unmarshal(*resp.Result, &res); err != nil {
+		return res, fmt.Errorf("failed to decode result for %s: %w", method, err)
+	}
+	return res, nil
+}
+
+// DecodeParams unmarshal params to v the way Handle and Register do, for handlers dealing with raw params,
+// i.e. generated by jrpcgen. Empty and null params are skipped, leaving v as is. Single param wrapped in array,
+// as json-rpc 2.0 client sends non-object param, decoded as the param itself if v is not a list.
+// Failed decoding reported as Error with ErrCodeInvalidParams code.
+func DecodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	err := json.Unmarshal(params, v)
+	if err == nil {
+		return nil
+	}
+	var single []json.RawMessage
+	if json.Unmarshal(params, &single) == nil && len(single) == 1 && json.Unmarshal(single[0], v) == nil {
+		return nil
+	}
+	return NewError(ErrCodeInvalidParams, "invalid params: "+err.Error())
+}
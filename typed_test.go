@@ -0,0 +1,162 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandle(t *testing.T) {
+	type reqData struct {
+		Name  string
+		Count int
+	}
+	type respData struct {
+		Greeting string
+	}
+
+	s := NewServer("/v1/cmd")
+	Handle(s, "greet", func(_ context.Context, p reqData) (respData, error) {
+		if p.Name == "" {
+			return respData{}, errors.New("no name")
+		}
+		return respData{Greeting: "hello " + p.Name}, nil
+	})
+	Handle(s, "count", func(_ context.Context, p []int) (int, error) {
+		return len(p), nil
+	})
+
+	url := startServer(t, s)
+	c := Client{API: url + "/v1/cmd", Client: http.Client{}}
+
+	t.Run("decoded and encoded", func(t *testing.T) {
+		r, err := c.Call("greet", reqData{Name: "user", Count: 1})
+		require.NoError(t, err)
+		res := respData{}
+		require.NoError(t, json.Unmarshal(*r.Result, &res))
+		assert.Equal(t, respData{Greeting: "hello user"}, res)
+		assert.Equal(t, uint64(1), r.ID)
+	})
+
+	t.Run("multiple args", func(t *testing.T) {
+		r, err := c.Call("count", 1, 2, 3)
+		require.NoError(t, err)
+		assert.JSONEq(t, "3", string(*r.Result))
+	})
+
+	t.Run("no params", func(t *testing.T) {
+		r, err := c.Call("count")
+		require.NoError(t, err)
+		assert.JSONEq(t, "0", string(*r.Result))
+	})
+
+	t.Run("handler error", func(t *testing.T) {
+		_, err := c.Call("greet", reqData{})
+		assert.EqualError(t, err, "no name")
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		_, err := c.Call("greet", "not an object")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid params: json: cannot unmarshal string")
+		assert.ErrorIs(t, err, ErrInvalidParams)
+		var rpcErr *Error
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, ErrCodeInvalidParams, rpcErr.Code)
+	})
+}
+
+func TestDecodeParams(t *testing.T) {
+	var s string
+	require.NoError(t, DecodeParams(json.RawMessage(`"abc"`), &s))
+	assert.Equal(t, "abc", s)
+	require.NoError(t, DecodeParams(json.RawMessage(`["def"]`), &s), "single param wrapped by json-rpc 2.0 client")
+	assert.Equal(t, "def", s)
+
+	var ints []int
+	require.NoError(t, DecodeParams(json.RawMessage(`[1]`), &ints))
+	assert.Equal(t, []int{1}, ints, "list not unwrapped")
+
+	err := DecodeParams(json.RawMessage(`["a","b"]`), &s)
+	require.ErrorIs(t, err, ErrInvalidParams)
+	assert.EqualError(t, err, "invalid params: json: cannot unmarshal array into Go value of type string")
+}
+
+func TestInvoke(t *testing.T) {
+	type respData struct {
+		Res1 string
+		Res2 bool
+	}
+
+	s := NewServer("/v1/cmd")
+	s.Add("obj", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, respData{Res1: "res blah", Res2: true}, nil)
+	})
+	s.Add("null", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, nil)
+	})
+	s.Add("empty", func(id uint64, params json.RawMessage) Response {
+		return Response{ID: id}
+	})
+	s.Add("fail", func(id uint64, params json.RawMessage) Response {
+		return EncodeResponse(id, nil, errors.New("some error"))
+	})
+
+	url := startServer(t, s)
+	c := &Client{API: url + "/v1/cmd", Client: http.Client{}}
+
+	t.Run("decoded", func(t *testing.T) {
+		res, err := Invoke[respData](context.Background(), c, "obj", 1, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, respData{Res1: "res blah", Res2: true}, res)
+	})
+
+	t.Run("decoded to pointer", func(t *testing.T) {
+		res, err := Invoke[*respData](context.Background(), c, "obj")
+		require.NoError(t, err)
+		assert.Equal(t, &respData{Res1: "res blah", Res2: true}, res)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		_, err := Invoke[int](context.Background(), c, "obj")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decode result for obj")
+	})
+
+	t.Run("null result", func(t *testing.T) {
+		_, err := Invoke[respData](context.Background(), c, "null")
+		require.ErrorIs(t, err, ErrNoResult)
+		assert.EqualError(t, err, "no result for null")
+	})
+
+	t.Run("missing result", func(t *testing.T) {
+		_, err := Invoke[respData](context.Background(), c, "empty")
+		require.ErrorIs(t, err, ErrNoResult)
+	})
+
+	t.Run("remote error", func(t *testing.T) {
+		_, err := Invoke[respData](context.Background(), c, "fail")
+		assert.EqualError(t, err, "some error")
+	})
+}
+
+func TestInvokeCanceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body) // body has to be consumed for the server to notice the client gone
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := Invoke[string](ctx, &Client{API: ts.URL}, "test")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,165 @@
+package jrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unixScheme is the scheme of Client endpoints served on unix domain socket, see RunUnix
+const unixScheme = "unix://"
+
+// RunUnix http server on unix domain socket with given file mode, i.e. 0o660 to let the group in, blocks until
+// Shutdown called or the server failed. Stale socket file left by a previous run removed, but the one in use by
+// a running server is not. The socket is never exposed with wider permissions, and removed on Shutdown.
+func (s *Server) RunUnix(path string, mode os.FileMode) error {
+
+	if len(s.funcs.m) == 0 {
+		return fmt.Errorf("nothing mapped for dispatch, Add has to be called prior to RunUnix")
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	s.activate()
+
+	ln, err := listenUnix(path, mode)
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ln)
+}
+
+// listenUnix listens on the socket made in a private directory next to path, so nobody else can connect before
+// the mode set, and moved to path after that. The socket file removed when the listener closed.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".jrpc") // made with 0o700 permissions
+	if err != nil {
+		return nil, fmt.Errorf("can't make directory for socket %s: %w", path, err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }() // empty once the socket moved
+	tmp := filepath.Join(dir, "s")
+
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("can't listen on socket %s: %w", path, err)
+	}
+	ln.SetUnlinkOnClose(false) // the socket moved, removed by unixListener.Close
+	if err = os.Chmod(tmp, mode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("can't set permissions of socket %s: %w", path, err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("can't move socket to %s: %w", path, err)
+	}
+	return &unixListener{UnixListener: ln, path: path}, nil
+}
+
+// unixListener removes the socket file moved to path on the first Close
+type unixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+// Close closes the listener and removes the socket file
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() {
+		if rmErr := os.Remove(l.path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) && err == nil {
+			err = rmErr
+		}
+	})
+	return err
+}
+
+// removeStaleSocket removes socket file nobody listens on. Fails if the file is not a socket or the socket in use.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't check socket %s: %w", path, err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("can't listen on socket %s, file exists and it is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("can't listen on socket %s, already in use", path)
+	}
+	if err = os.Remove(path); err != nil {
+		return fmt.Errorf("can't remove stale socket %s: %w", path, err)
+	}
+	return nil
+}
+
+// unixEndpoint splits endpoint in unix:///path/to/socket:/api form to the socket path and http url of the api.
+// Returns false for other endpoints.
+func unixEndpoint(endpoint string) (socket, url string, ok bool) {
+	rest, ok := strings.CutPrefix(endpoint, unixScheme)
+	if !ok {
+		return "", "", false
+	}
+	socket, api := rest, "/"
+	if i := strings.LastIndex(rest, ":/"); i > 0 {
+		socket, api = rest[:i], rest[i+1:]
+	}
+	return socket, "http://unix" + api, true
+}
+
+// httpClient returns http client and url for the endpoint. Unix endpoints get the client dialing the socket,
+// made from Client.Client with the transport cloned for each socket.
+func (r *Client) httpClient(endpoint string) (*http.Client, string, error) {
+	socket, url, ok := unixEndpoint(endpoint)
+	if !ok {
+		return &r.Client, endpoint, nil
+	}
+
+	r.unix.Lock()
+	defer r.unix.Unlock()
+	if hc, found := r.unix.clients[socket]; found {
+		return hc, url, nil
+	}
+
+	base := http.DefaultTransport
+	if r.Client.Transport != nil {
+		base = r.Client.Transport
+	}
+	tr, ok := base.(*http.Transport)
+	if !ok {
+		return nil, "", fmt.Errorf("unix socket endpoint requires *http.Transport, got %T", base)
+	}
+	tr = tr.Clone()
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+
+	hc := r.Client // copy keeps timeout, cookie jar and redirect policy of the user's client
+	hc.Transport = tr
+	if r.unix.clients == nil {
+		r.unix.clients = map[string]*http.Client{}
+	}
+	r.unix.clients[socket] = &hc
+	return &hc, url, nil
+}
+
+// unixClients keeps http clients of unix socket endpoints, made on the first use
+type unixClients struct {
+	sync.Mutex
+	clients map[string]*http.Client
+}
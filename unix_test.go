@@ -0,0 +1,158 @@
+package jrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerRunUnix(t *testing.T) {
+	socket := socketPath(t)
+	s := NewServer("/v1/cmd", Auth("user", "passwd"))
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "unix", nil) })
+	runUnix(t, s, socket, 0o660)
+
+	fi, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), fi.Mode().Perm())
+	entries, err := os.ReadDir(filepath.Dir(socket))
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "private dir of the socket removed")
+	assert.Equal(t, "plugin.sock", entries[0].Name())
+
+	c := &Client{API: "unix://" + socket + ":/v1/cmd", AuthUser: "user", AuthPasswd: "passwd"}
+	for range 3 {
+		v, err := Invoke[string](context.Background(), c, "fn")
+		require.NoError(t, err)
+		assert.Equal(t, "unix", v)
+	}
+	assert.Len(t, c.unix.clients, 1, "http client reused")
+
+	_, err = (&Client{API: "unix://" + socket + ":/v1/other", AuthUser: "user", AuthPasswd: "passwd"}).Call("fn")
+	assert.EqualError(t, err, "bad status 404 Not Found for fn")
+
+	// socket in use is not removed
+	s2 := NewServer("/v1/cmd")
+	s2.Add("fn", func(id uint64, _ json.RawMessage) Response { return Response{} })
+	assert.EqualError(t, s2.RunUnix(socket, 0o660), "can't listen on socket "+socket+", already in use")
+}
+
+func TestServerRunUnixStaleSocket(t *testing.T) {
+	socket := socketPath(t)
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, l.Close())
+	_, err = os.Stat(socket)
+	require.NoError(t, err, "stale socket left")
+
+	s := NewServer("/v1/cmd")
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "ok", nil) })
+	runUnix(t, s, socket, 0o600)
+
+	fi, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm(), "mode set by the caller")
+
+	v, err := Invoke[string](context.Background(), &Client{API: "unix://" + socket + ":/v1/cmd"}, "fn")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", v)
+}
+
+func TestServerRunUnixFailed(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	assert.EqualError(t, s.RunUnix(socketPath(t), 0o660), "nothing mapped for dispatch, Add has to be called prior to RunUnix")
+
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return Response{} })
+	file := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+	assert.EqualError(t, s.RunUnix(file, 0o660), "can't listen on socket "+file+", file exists and it is not a socket")
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data), "regular file kept")
+}
+
+func TestServerServe(t *testing.T) {
+	s := NewServer("/v1/cmd")
+	assert.EqualError(t, s.Serve(listen(t)), "nothing mapped for dispatch, Add has to be called prior to Serve")
+
+	s.Add("fn", func(id uint64, _ json.RawMessage) Response { return EncodeResponse(id, "ok", nil) })
+	l := listen(t)
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+
+	v, err := Invoke[string](context.Background(), &Client{API: "http://" + l.Addr().String() + "/v1/cmd"}, "fn")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", v)
+
+	http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+	require.NoError(t, s.Shutdown())
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+}
+
+func TestUnixEndpoint(t *testing.T) {
+	tbl := []struct {
+		endpoint, socket, url string
+		ok                    bool
+	}{
+		{"unix:///tmp/plugin.sock:/command", "/tmp/plugin.sock", "http://unix/command", true},
+		{"unix:///tmp/plugin.sock:/v1/cmd", "/tmp/plugin.sock", "http://unix/v1/cmd", true},
+		{"unix:///tmp/plugin.sock", "/tmp/plugin.sock", "http://unix/", true},
+		{"unix://plugin.sock:/command", "plugin.sock", "http://unix/command", true},
+		{"http://127.0.0.1:8080/command", "", "", false},
+	}
+	for _, tt := range tbl {
+		socket, url, ok := unixEndpoint(tt.endpoint)
+		assert.Equal(t, tt.ok, ok, tt.endpoint)
+		assert.Equal(t, tt.socket, socket, tt.endpoint)
+		assert.Equal(t, tt.url, url, tt.endpoint)
+	}
+}
+
+func TestClientUnixCustomTransport(t *testing.T) {
+	c := &Client{API: "unix:///tmp/no.sock:/command", Client: http.Client{Transport: roundTripFunc(nil)}}
+	_, err := c.Call("fn")
+	assert.EqualError(t, err, "failed to make request for fn: unix socket endpoint requires *http.Transport, got jrpc.roundTripFunc")
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// runUnix runs the server on unix socket with given mode and waits for it to accept connections
+func runUnix(t *testing.T, s *Server, socket string, mode os.FileMode) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- s.RunUnix(socket, mode) }()
+	t.Cleanup(func() {
+		assert.NoError(t, s.Shutdown())
+		assert.ErrorIs(t, <-done, http.ErrServerClosed)
+		_, err := os.Stat(socket)
+		assert.ErrorIs(t, err, os.ErrNotExist, "socket removed on shutdown")
+	})
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
+// socketPath returns path of socket in a short temp dir, as socket path length is limited
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "jrpc")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "plugin.sock")
+}